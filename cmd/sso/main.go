@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"flag"
 	"log/slog"
 	"os"
 	"os/signal"
@@ -9,6 +10,7 @@ import (
 
 	"github.com/j0n1que/sso-service/internal/app"
 	"github.com/j0n1que/sso-service/internal/config"
+	"github.com/j0n1que/sso-service/internal/lib/tracing"
 )
 
 const (
@@ -19,45 +21,120 @@ const (
 func main() {
 	cfg := config.MustLoad()
 
-	log := setupLogger(cfg.Env)
+	level := new(slog.LevelVar)
+	level.Set(logLevel(cfg))
 
-	log.Info("starting service")
+	log := setupLogger(logFormat(cfg), level)
+
+	log.Info("config loaded", slog.String("config", cfg.String()))
 
 	ctx := context.TODO()
 
-	application := app.New(ctx, log, cfg.GRPC.Port, cfg.UsersStorage, app.TokensStorage{
-		Addr:     cfg.TokensStorage.Addr,
-		Password: cfg.TokensStorage.Password,
-	}, cfg.TokenTTL)
+	if args := flag.Args(); len(args) > 0 {
+		if args[0] != "migrate" {
+			log.Error("unknown command", slog.String("command", args[0]))
+			os.Exit(2)
+		}
+		if err := runMigrate(ctx, log, cfg, args[1:]); err != nil {
+			log.Error("failed to migrate", slog.String("error", err.Error()))
+			os.Exit(1)
+		}
+		return
+	}
+
+	log.Info("starting service")
+
+	application := app.New(ctx, log, cfg)
+
+	watcher := config.NewWatcher(log, cfg)
+	watcher.Subscribe(application.Reload)
+	watcher.Subscribe(func(cfg *config.Config) {
+		level.Set(logLevel(cfg))
+	})
+
+	go watcher.Run()
 
 	go func() {
 		application.GRPCSrv.MustRun()
 	}()
 
+	go func() {
+		application.GatewaySrv.MustRun()
+	}()
+
+	go func() {
+		application.HealthSrv.MustRun()
+	}()
+
+	go func() {
+		application.MetricsSrv.MustRun()
+	}()
+
+	go application.PurgerSrv.Run()
+
+	go application.OutboxSrv.Run()
+
+	go application.WebhookSrv.Run()
+
 	stop := make(chan os.Signal, 1)
 	signal.Notify(stop, syscall.SIGTERM, syscall.SIGINT)
 
 	sign := <-stop
 
 	log.Info("stopping service", slog.String("signal", sign.String()))
-	application.RedisSrv.Close()
-	application.MongoSrv.Disconnect(ctx)
+	watcher.Stop()
+	application.GatewaySrv.Stop()
+	application.HealthSrv.Stop()
+	application.MetricsSrv.Stop()
+	application.PurgerSrv.Stop()
+	application.OutboxSrv.Stop()
+	application.WebhookSrv.Stop()
+	application.EventsSrv.Close()
+	application.TokensSrv.Close()
+	application.StorageSrv.Close(ctx)
 	application.GRPCSrv.Stop()
+	application.TracingSrv.Shutdown(ctx)
 
 	log.Info("service stopped")
 }
 
-func setupLogger(env string) *slog.Logger {
-	var log *slog.Logger
-	switch env {
-	case envLocal:
-		log = slog.New(
-			slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
-		)
-	case envProd:
-		log = slog.New(
-			slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelInfo}),
-		)
+func setupLogger(format string, level slog.Leveler) *slog.Logger {
+	opts := &slog.HandlerOptions{Level: level}
+
+	var handler slog.Handler
+	switch format {
+	case "json":
+		handler = slog.NewJSONHandler(os.Stdout, opts)
+	default:
+		handler = slog.NewTextHandler(os.Stdout, opts)
+	}
+
+	return slog.New(tracing.NewLogHandler(handler))
+}
+
+// logFormat returns the configured format, or json in prod and text in any
+// other environment.
+func logFormat(cfg *config.Config) string {
+	if cfg.Log.Format != "" {
+		return cfg.Log.Format
+	}
+
+	if cfg.Env == envProd {
+		return "json"
+	}
+	return "text"
+}
+
+// logLevel returns the configured level, or the default of the environment.
+func logLevel(cfg *config.Config) slog.Level {
+	if cfg.Log.Level != "" {
+		// validated when the config was loaded
+		level, _ := config.ParseLevel(cfg.Log.Level)
+		return level
+	}
+
+	if cfg.Env == envLocal {
+		return slog.LevelDebug
 	}
-	return log
+	return slog.LevelInfo
 }
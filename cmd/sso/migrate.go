@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+
+	"github.com/j0n1que/sso-service/internal/config"
+	mongodb "github.com/j0n1que/sso-service/internal/storage/mongo"
+	"github.com/j0n1que/sso-service/internal/storage/postgres"
+)
+
+const migrateUsage = "usage: sso [-config path] migrate up|down [-steps n] [-dry-run]"
+
+// runMigrate applies or rolls back schema migrations of the configured
+// storage. args are the arguments following the migrate subcommand.
+func runMigrate(ctx context.Context, log *slog.Logger, cfg *config.Config, args []string) error {
+	if len(args) == 0 {
+		return errors.New(migrateUsage)
+	}
+
+	direction := args[0]
+
+	flags := flag.NewFlagSet("migrate "+direction, flag.ContinueOnError)
+	steps := flags.Int("steps", 1, "number of migrations to roll back")
+	dryRun := flags.Bool("dry-run", false, "only list the migrations that would run")
+	if err := flags.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	switch cfg.Storage {
+	case "mongo":
+		client, err := mongodb.Connect(ctx, cfg.UsersStorage, cfg.Mongo)
+		if err != nil {
+			return fmt.Errorf("no connection to mongodb: %w", err)
+		}
+		defer client.Disconnect(ctx)
+
+		migrator := mongodb.NewMigrator(log, mongodb.NewDatabase(client, cfg.Mongo.Database, cfg.Mongo.CollectionPrefix))
+
+		var migrations []mongodb.Migration
+		switch direction {
+		case "up":
+			migrations, err = migrator.Up(ctx, *dryRun)
+		case "down":
+			migrations, err = migrator.Down(ctx, *steps, *dryRun)
+		default:
+			return errors.New(migrateUsage)
+		}
+		if err != nil {
+			return err
+		}
+
+		log.Info("migrations done", slog.String("direction", direction), slog.Int("count", len(migrations)), slog.Bool("dry_run", *dryRun))
+
+		return nil
+	case "postgres":
+		if direction != "up" || *dryRun {
+			return errors.New("postgres migrations can only be applied, run migrate up without -dry-run")
+		}
+
+		pool, err := postgres.Connect(ctx, cfg.Postgres.DSN, cfg.Postgres.MaxConns)
+		if err != nil {
+			return fmt.Errorf("no connection to postgres: %w", err)
+		}
+		defer pool.Close()
+
+		if err := postgres.Migrate(ctx, pool); err != nil {
+			return err
+		}
+
+		log.Info("migrations done", slog.String("direction", direction))
+
+		return nil
+	default:
+		return fmt.Errorf("storage %q has no migrations", cfg.Storage)
+	}
+}
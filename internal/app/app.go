@@ -5,45 +5,129 @@ import (
 	"log/slog"
 	"time"
 
+	ssov1 "github.com/j0n1que/sso-protos/gen/go"
+	gatewayapp "github.com/j0n1que/sso-service/internal/app/gateway"
 	grpcapp "github.com/j0n1que/sso-service/internal/app/grpc"
+	healthapp "github.com/j0n1que/sso-service/internal/app/health"
+	metricsapp "github.com/j0n1que/sso-service/internal/app/metrics"
+	outboxapp "github.com/j0n1que/sso-service/internal/app/outbox"
+	purgerapp "github.com/j0n1que/sso-service/internal/app/purger"
+	webhookapp "github.com/j0n1que/sso-service/internal/app/webhook"
+	"github.com/j0n1que/sso-service/internal/config"
+	memevents "github.com/j0n1que/sso-service/internal/events/memory"
+	eventsredis "github.com/j0n1que/sso-service/internal/events/redis"
+	"github.com/j0n1que/sso-service/internal/lib/jwt"
+	"github.com/j0n1que/sso-service/internal/lib/tracing"
 	"github.com/j0n1que/sso-service/internal/services/auth"
-	mongodb "github.com/j0n1que/sso-service/internal/storage/mongo"
-	"github.com/j0n1que/sso-service/internal/storage/redis"
-	"go.mongodb.org/mongo-driver/mongo"
-	"go.mongodb.org/mongo-driver/mongo/options"
+	"github.com/j0n1que/sso-service/internal/services/webhooks"
+	redisstorage "github.com/j0n1que/sso-service/internal/storage/redis"
 )
 
-type App struct {
-	GRPCSrv  *grpcapp.App
-	MongoSrv *mongo.Client
-	RedisSrv *redis.TokenStorage
+const (
+	purgeInterval = time.Hour
+
+	publisherRedis  = "redis"
+	publisherMemory = "memory"
+)
+
+type EventPublisher interface {
+	outboxapp.Publisher
+	Close()
 }
 
-type TokensStorage struct {
-	Addr     string
-	Password string
+type App struct {
+	GRPCSrv    *grpcapp.App
+	GatewaySrv *gatewayapp.App
+	HealthSrv  *healthapp.App
+	MetricsSrv *metricsapp.App
+	PurgerSrv  *purgerapp.App
+	OutboxSrv  *outboxapp.App
+	WebhookSrv *webhookapp.App
+	StorageSrv *Storage
+	TokensSrv  TokenStore
+	EventsSrv  EventPublisher
+	TracingSrv *tracing.Provider
+
+	auth *auth.Auth
 }
 
-func New(ctx context.Context, log *slog.Logger, grpcPort int, userStorageCredentials string, tokenStorageCredentials TokensStorage, tokenTTL time.Duration) *App {
-	mongoClient, err := mongo.Connect(ctx, options.Client().ApplyURI(userStorageCredentials))
+func New(ctx context.Context, log *slog.Logger, cfg *config.Config) *App {
+	tracingProvider, err := tracing.New(ctx, cfg.Tracing)
 	if err != nil {
-		panic("no connection to mongodb" + err.Error())
+		panic("failed to set up tracing" + err.Error())
 	}
 
-	userDAO := mongodb.New(ctx, mongoClient)
+	tokenManager, err := jwt.New(cfg.JWT)
+	if err != nil {
+		panic("invalid jwt config: " + err.Error())
+	}
+
+	store := mustStorage(ctx, log, cfg)
+	withUserCache(ctx, cfg, store)
+
+	tokens, tokenProbes := mustTokenStore(ctx, log, cfg)
 
-	if err := userDAO.EnsureIndexes(ctx); err != nil {
-		panic("failed to set indexation for users database" + err.Error())
+	var publisher EventPublisher
+	switch cfg.Events.Publisher {
+	case publisherRedis:
+		client, err := redisstorage.Connect(ctx, cfg.TokensStorage)
+		if err != nil {
+			panic("no connection to redis for events: " + err.Error())
+		}
+		publisher = eventsredis.New(client, cfg.Events.Stream)
+	case publisherMemory:
+		publisher = memevents.New()
+	default:
+		panic("unknown events publisher: " + cfg.Events.Publisher)
 	}
 
-	redisclient := redis.New(tokenStorageCredentials.Addr, tokenStorageCredentials.Password)
+	authService := auth.New(log, tokenManager, store.Users, store.Users, tokens, store.Audit, store.Outbox, store.Tx, cfg.TokenTTL, cfg.UserRetention)
 
-	authService := auth.New(log, userDAO, userDAO, redisclient, tokenTTL)
+	webhooksService := webhooks.New(log, store.Webhooks)
+
+	healthApp := healthapp.New(log, append(store.Probes, tokenProbes...), healthapp.Options{
+		Port:     cfg.Health.Port,
+		Interval: cfg.Health.Interval,
+		Timeout:  cfg.Health.Timeout,
+		Services: []string{ssov1.Auth_ServiceDesc.ServiceName},
+	})
+
+	grpcApp := grpcapp.New(log, cfg.GRPC, authService, webhooksService, healthApp.HealthServer(), tokens, store.Access, authService)
+
+	gatewayApp := gatewayapp.New(log, grpcApp, cfg.Gateway)
+
+	metricsApp := metricsapp.New(log, tokens, cfg.Metrics.Port, cfg.Metrics.SessionsInterval)
+
+	purgerApp := purgerapp.New(log, authService, purgeInterval)
+
+	outboxApp := outboxapp.New(log, store.Outbox, []outboxapp.Publisher{publisher, webhooksService}, cfg.Events.PollInterval)
+
+	webhookApp := webhookapp.New(log, store.Webhooks, webhookapp.Options{
+		Timeout:      cfg.Webhooks.Timeout,
+		MaxAttempts:  cfg.Webhooks.MaxAttempts,
+		BaseBackoff:  cfg.Webhooks.BaseBackoff,
+		MaxBackoff:   cfg.Webhooks.MaxBackoff,
+		PollInterval: cfg.Webhooks.PollInterval,
+	})
 
-	grpcApp := grpcapp.New(log, grpcPort, authService, redisclient, userDAO)
 	return &App{
-		GRPCSrv:  grpcApp,
-		MongoSrv: mongoClient,
-		RedisSrv: redisclient,
+		GRPCSrv:    grpcApp,
+		GatewaySrv: gatewayApp,
+		HealthSrv:  healthApp,
+		MetricsSrv: metricsApp,
+		PurgerSrv:  purgerApp,
+		OutboxSrv:  outboxApp,
+		WebhookSrv: webhookApp,
+		StorageSrv: store,
+		TokensSrv:  tokens,
+		EventsSrv:  publisher,
+		TracingSrv: tracingProvider,
+		auth:       authService,
 	}
 }
+
+// Reload applies a new config to the parts of the service that support it.
+func (a *App) Reload(cfg *config.Config) {
+	a.auth.SetTTLs(cfg.TokenTTL, cfg.UserRetention)
+	a.GRPCSrv.Reload(cfg.GRPC)
+}
@@ -0,0 +1,150 @@
+package gatewayapp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/j0n1que/sso-service/internal/config"
+	"github.com/j0n1que/sso-service/internal/lib/tlsconfig"
+)
+
+// Invoker runs an Auth RPC in-process through the gRPC interceptor chain.
+type Invoker interface {
+	Invoke(ctx context.Context, method string, dec func(interface{}) error) (interface{}, error)
+}
+
+// App serves the Auth RPCs as REST/JSON endpoints together with their
+// OpenAPI description.
+type App struct {
+	log        *slog.Logger
+	invoker    Invoker
+	port       int
+	cors       config.CORSConfig
+	tls        bool
+	httpServer *http.Server
+}
+
+func New(log *slog.Logger, invoker Invoker, cfg config.GatewayConfig) *App {
+	a := &App{
+		log:     log,
+		invoker: invoker,
+		port:    cfg.Port,
+		cors:    cfg.CORS,
+	}
+
+	doc, err := openAPI(routes)
+	if err != nil {
+		panic("failed to build OpenAPI document: " + err.Error())
+	}
+
+	mux := http.NewServeMux()
+	for _, rt := range routes {
+		mux.Handle(rt.pattern(), a.handle(rt))
+	}
+	mux.HandleFunc("GET /openapi.json", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(doc)
+	})
+
+	a.httpServer = &http.Server{
+		Addr:              fmt.Sprintf(":%d", cfg.Port),
+		Handler:           a.withCORS(mux),
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+
+	switch {
+	case cfg.TLS.Enabled():
+		reloader, err := tlsconfig.New(log, cfg.TLS)
+		if err != nil {
+			panic("failed to load gateway TLS certificates: " + err.Error())
+		}
+		a.httpServer.TLSConfig = reloader.Config()
+		a.tls = true
+	case cfg.Insecure:
+		log.Warn("REST gateway is not using TLS, passwords are sent in plaintext")
+	default:
+		panic("REST gateway has no TLS certificates and is not allowed to serve plain HTTP")
+	}
+
+	return a
+}
+
+func (a *App) MustRun() {
+	if err := a.Run(); err != nil {
+		panic(err)
+	}
+}
+
+func (a *App) Run() error {
+	const op = "gatewayapp.Run"
+
+	log := a.log.With(
+		slog.String("op", op),
+		slog.Int("port", a.port),
+	)
+
+	log.Info("REST gateway is running", slog.String("addr", a.httpServer.Addr), slog.Bool("tls", a.tls))
+
+	var err error
+	if a.tls {
+		// the certificates come from TLSConfig
+		err = a.httpServer.ListenAndServeTLS("", "")
+	} else {
+		err = a.httpServer.ListenAndServe()
+	}
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (a *App) Stop() {
+	const op = "gatewayapp.Stop"
+
+	a.log.With(slog.String("op", op)).
+		Info("stopping REST gateway", slog.Int("port", a.port))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	a.httpServer.Shutdown(ctx)
+}
+
+// withCORS answers preflight requests and sets CORS headers for the
+// configured origins.
+func (a *App) withCORS(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		if origin == "" || !a.originAllowed(origin) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		h := w.Header()
+		h.Set("Access-Control-Allow-Origin", origin)
+		h.Add("Vary", "Origin")
+		h.Set("Access-Control-Expose-Headers", "X-Request-Id")
+
+		if r.Method != http.MethodOptions || r.Header.Get("Access-Control-Request-Method") == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		h.Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE")
+		h.Set("Access-Control-Allow-Headers", strings.Join(a.cors.AllowedHeaders, ", "))
+		h.Set("Access-Control-Max-Age", strconv.Itoa(int(a.cors.MaxAge.Seconds())))
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
+
+func (a *App) originAllowed(origin string) bool {
+	return slices.Contains(a.cors.AllowedOrigins, "*") || slices.Contains(a.cors.AllowedOrigins, origin)
+}
@@ -0,0 +1,244 @@
+package gatewayapp
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"strings"
+
+	"github.com/j0n1que/sso-service/internal/lib/requestid"
+	"github.com/j0n1que/sso-service/internal/lib/tracing"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+const (
+	maxBodySize = 1 << 20
+
+	// metadataHeaderPrefix marks headers passed to the RPC as metadata
+	// under the rest of their name.
+	metadataHeaderPrefix = "Grpc-Metadata-"
+)
+
+// forwardedHeaders are passed to the RPC as the metadata keys the gRPC
+// clients use.
+var forwardedHeaders = map[string]string{
+	"Authorization":  "authorization",
+	"Telegram-Login": "telegramlogin",
+	"Telegramlogin":  "telegramlogin",
+	"User-Agent":     "user-agent",
+	"X-Request-Id":   "x-request-id",
+}
+
+var marshalOpts = protojson.MarshalOptions{UseProtoNames: true, EmitUnpopulated: true}
+
+func (a *App) handle(rt route) http.Handler {
+	params := rt.pathParams()
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+		ctx, span := tracing.Start(ctx, rt.fullMethod(), trace.WithSpanKind(trace.SpanKindServer))
+		defer span.End()
+
+		// the ID is settled here so that errors written by the gateway carry it too
+		id := requestid.OrNew(r.Header.Get("X-Request-Id"))
+		w.Header().Set("X-Request-Id", id)
+
+		md := incomingMetadata(r)
+		md.Set(requestid.MetadataKey, id)
+
+		ctx = metadata.NewIncomingContext(ctx, md)
+		ctx = peer.NewContext(ctx, &peer.Peer{Addr: remoteAddr(r)})
+
+		var body []byte
+		if rt.body {
+			var err error
+			body, err = io.ReadAll(io.LimitReader(r.Body, maxBodySize))
+			if err != nil {
+				writeError(w, status.Error(codes.InvalidArgument, "failed to read request body"))
+				return
+			}
+		}
+
+		dec := func(v interface{}) error {
+			msg := v.(proto.Message)
+
+			if len(body) > 0 {
+				if err := protojson.Unmarshal(body, msg); err != nil {
+					return status.Errorf(codes.InvalidArgument, "invalid request body: %v", err)
+				}
+			}
+
+			for _, name := range params {
+				if err := setField(msg.ProtoReflect(), name, []string{r.PathValue(name)}); err != nil {
+					return status.Error(codes.InvalidArgument, err.Error())
+				}
+			}
+
+			for name, values := range r.URL.Query() {
+				if err := setField(msg.ProtoReflect(), name, values); err != nil {
+					return status.Error(codes.InvalidArgument, err.Error())
+				}
+			}
+
+			return nil
+		}
+
+		resp, err := a.invoker.Invoke(ctx, rt.rpc, dec)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+
+		writeMessage(w, http.StatusOK, resp.(proto.Message))
+	})
+}
+
+func incomingMetadata(r *http.Request) metadata.MD {
+	md := metadata.MD{}
+
+	for key, values := range r.Header {
+		if name, ok := forwardedHeaders[key]; ok {
+			md.Append(name, values...)
+			continue
+		}
+		if name, ok := strings.CutPrefix(key, metadataHeaderPrefix); ok {
+			md.Append(name, values...)
+		}
+	}
+
+	return md
+}
+
+func remoteAddr(r *http.Request) net.Addr {
+	addrPort, err := netip.ParseAddrPort(r.RemoteAddr)
+	if err != nil {
+		return &net.TCPAddr{}
+	}
+
+	return net.TCPAddrFromAddrPort(addrPort)
+}
+
+// setField sets the request field called name from its text form.
+func setField(msg protoreflect.Message, name string, values []string) error {
+	fd := msg.Descriptor().Fields().ByName(protoreflect.Name(name))
+	if fd == nil || len(values) == 0 {
+		return fmt.Errorf("unknown parameter %q", name)
+	}
+
+	if fd.IsList() {
+		list := msg.Mutable(fd).List()
+		for _, v := range values {
+			value, err := parseValue(msg, fd, v)
+			if err != nil {
+				return err
+			}
+			list.Append(value)
+		}
+
+		return nil
+	}
+
+	value, err := parseValue(msg, fd, values[len(values)-1])
+	if err != nil {
+		return err
+	}
+	msg.Set(fd, value)
+
+	return nil
+}
+
+func parseValue(msg protoreflect.Message, fd protoreflect.FieldDescriptor, s string) (protoreflect.Value, error) {
+	switch fd.Kind() {
+	case protoreflect.StringKind:
+		return protoreflect.ValueOfString(s), nil
+	case protoreflect.BoolKind:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return protoreflect.Value{}, fmt.Errorf("invalid %s: %w", fd.Name(), err)
+		}
+		return protoreflect.ValueOfBool(b), nil
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		n, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return protoreflect.Value{}, fmt.Errorf("invalid %s: %w", fd.Name(), err)
+		}
+		return protoreflect.ValueOfInt64(n), nil
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		n, err := strconv.ParseInt(s, 10, 32)
+		if err != nil {
+			return protoreflect.Value{}, fmt.Errorf("invalid %s: %w", fd.Name(), err)
+		}
+		return protoreflect.ValueOfInt32(int32(n)), nil
+	case protoreflect.MessageKind:
+		// well-known types such as Timestamp have a string JSON form
+		value := msg.NewField(fd)
+		raw, _ := json.Marshal(s)
+		if fd.IsList() || protojson.Unmarshal(raw, value.Message().Interface()) != nil {
+			return protoreflect.Value{}, fmt.Errorf("invalid %s", fd.Name())
+		}
+		return value, nil
+	}
+
+	return protoreflect.Value{}, fmt.Errorf("parameter %s cannot be set from the URL", fd.Name())
+}
+
+func writeMessage(w http.ResponseWriter, code int, msg proto.Message) {
+	data, err := marshalOpts.Marshal(msg)
+	if err != nil {
+		http.Error(w, "failed to encode response", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	w.Write(data)
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	st := status.Convert(err)
+
+	writeMessage(w, httpStatus(st.Code()), st.Proto())
+}
+
+// httpStatus maps gRPC codes the way grpc-gateway does.
+func httpStatus(code codes.Code) int {
+	switch code {
+	case codes.OK:
+		return http.StatusOK
+	case codes.Canceled:
+		return 499
+	case codes.InvalidArgument, codes.FailedPrecondition, codes.OutOfRange:
+		return http.StatusBadRequest
+	case codes.DeadlineExceeded:
+		return http.StatusGatewayTimeout
+	case codes.NotFound:
+		return http.StatusNotFound
+	case codes.AlreadyExists, codes.Aborted:
+		return http.StatusConflict
+	case codes.PermissionDenied:
+		return http.StatusForbidden
+	case codes.Unauthenticated:
+		return http.StatusUnauthorized
+	case codes.ResourceExhausted:
+		return http.StatusTooManyRequests
+	case codes.Unimplemented:
+		return http.StatusNotImplemented
+	case codes.Unavailable:
+		return http.StatusServiceUnavailable
+	}
+
+	return http.StatusInternalServerError
+}
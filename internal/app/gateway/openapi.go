@@ -0,0 +1,197 @@
+package gatewayapp
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	ssov1 "github.com/j0n1que/sso-protos/gen/go"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+type object = map[string]any
+
+// openAPI describes routes as an OpenAPI 3 document whose schemas are
+// derived from the proto descriptors, so it cannot drift from the API.
+func openAPI(routes []route) ([]byte, error) {
+	service := ssov1.File_video_sso_proto.Services().ByName("Auth")
+	if service == nil {
+		return nil, fmt.Errorf("service %s not found", ssov1.Auth_ServiceDesc.ServiceName)
+	}
+
+	schemas := object{
+		"Status": object{
+			"type": "object",
+			"properties": object{
+				"code":    object{"type": "integer", "format": "int32"},
+				"message": object{"type": "string"},
+				"details": object{"type": "array", "items": object{"type": "object"}},
+			},
+		},
+	}
+	paths := object{}
+
+	for _, rt := range routes {
+		method := service.Methods().ByName(protoreflect.Name(rt.rpc))
+		if method == nil {
+			return nil, fmt.Errorf("rpc %s not found", rt.rpc)
+		}
+
+		addSchema(schemas, method.Input())
+		addSchema(schemas, method.Output())
+
+		pathParams := rt.pathParams()
+		parameters := []object{}
+		for _, name := range pathParams {
+			fd := method.Input().Fields().ByName(protoreflect.Name(name))
+			if fd == nil {
+				return nil, fmt.Errorf("rpc %s has no field %s", rt.rpc, name)
+			}
+			parameters = append(parameters, object{
+				"name":     name,
+				"in":       "path",
+				"required": true,
+				"schema":   fieldSchema(fd),
+			})
+		}
+
+		operation := object{
+			"operationId": rt.rpc,
+			"responses": object{
+				"200": object{
+					"description": "OK",
+					"content":     jsonContent(method.Output()),
+				},
+				"default": object{
+					"description": "Error",
+					"content":     object{"application/json": object{"schema": ref("Status")}},
+				},
+			},
+		}
+
+		if rt.body {
+			operation["requestBody"] = object{
+				"required": true,
+				"content":  jsonContent(method.Input()),
+			}
+		} else {
+			fields := method.Input().Fields()
+			for i := 0; i < fields.Len(); i++ {
+				fd := fields.Get(i)
+				if slices.Contains(pathParams, string(fd.Name())) {
+					continue
+				}
+				parameters = append(parameters, object{
+					"name":   string(fd.Name()),
+					"in":     "query",
+					"schema": fieldSchema(fd),
+				})
+			}
+		}
+
+		if len(parameters) > 0 {
+			operation["parameters"] = parameters
+		}
+
+		item, _ := paths[rt.path].(object)
+		if item == nil {
+			item = object{}
+			paths[rt.path] = item
+		}
+		item[strings.ToLower(rt.method)] = operation
+	}
+
+	return json.MarshalIndent(object{
+		"openapi": "3.0.3",
+		"info": object{
+			"title":   "SSO Auth API",
+			"version": "v1",
+		},
+		"paths": paths,
+		"components": object{
+			"schemas": schemas,
+			"securitySchemes": object{
+				"telegramLogin": object{
+					"type": "apiKey",
+					"in":   "header",
+					"name": "Telegram-Login",
+				},
+			},
+		},
+		"security": []object{{"telegramLogin": []string{}}},
+	}, "", "  ")
+}
+
+func jsonContent(md protoreflect.MessageDescriptor) object {
+	return object{"application/json": object{"schema": ref(string(md.Name()))}}
+}
+
+func ref(name string) object {
+	return object{"$ref": "#/components/schemas/" + name}
+}
+
+func addSchema(schemas object, md protoreflect.MessageDescriptor) {
+	name := string(md.Name())
+	if _, ok := schemas[name]; ok {
+		return
+	}
+
+	properties := object{}
+	schemas[name] = object{"type": "object", "properties": properties}
+
+	fields := md.Fields()
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		properties[string(fd.Name())] = fieldSchema(fd)
+
+		if fd.Kind() == protoreflect.MessageKind && !wellKnown(fd.Message()) {
+			addSchema(schemas, fd.Message())
+		}
+	}
+}
+
+func fieldSchema(fd protoreflect.FieldDescriptor) object {
+	var schema object
+
+	switch fd.Kind() {
+	case protoreflect.StringKind:
+		schema = object{"type": "string"}
+	case protoreflect.BytesKind:
+		schema = object{"type": "string", "format": "byte"}
+	case protoreflect.BoolKind:
+		schema = object{"type": "boolean"}
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind,
+		protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		// protojson encodes 64-bit integers as strings
+		schema = object{"type": "string", "format": "int64"}
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind,
+		protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		schema = object{"type": "integer", "format": "int32"}
+	case protoreflect.FloatKind, protoreflect.DoubleKind:
+		schema = object{"type": "number"}
+	case protoreflect.EnumKind:
+		schema = object{"type": "string"}
+	case protoreflect.MessageKind:
+		switch fd.Message().FullName() {
+		case "google.protobuf.Timestamp":
+			schema = object{"type": "string", "format": "date-time"}
+		case "google.protobuf.Empty":
+			schema = object{"type": "object"}
+		default:
+			schema = ref(string(fd.Message().Name()))
+		}
+	default:
+		schema = object{}
+	}
+
+	if fd.IsList() {
+		return object{"type": "array", "items": schema}
+	}
+
+	return schema
+}
+
+func wellKnown(md protoreflect.MessageDescriptor) bool {
+	return md.ParentFile().Package() == "google.protobuf"
+}
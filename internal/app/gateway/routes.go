@@ -0,0 +1,64 @@
+package gatewayapp
+
+import (
+	"regexp"
+
+	ssov1 "github.com/j0n1que/sso-protos/gen/go"
+)
+
+// route maps an HTTP endpoint onto an Auth RPC. Path parameters and query
+// parameters are copied into the request fields of the same name; when body
+// is set the request body is decoded as the JSON form of the request.
+type route struct {
+	method string
+	path   string
+	rpc    string
+	body   bool
+}
+
+var routes = []route{
+	{method: "POST", path: "/v1/users", rpc: "RegisterNewUser", body: true},
+	{method: "POST", path: "/v1/login", rpc: "AuthorizeUser", body: true},
+	{method: "GET", path: "/v1/users", rpc: "GetAllUsers"},
+	{method: "GET", path: "/v1/telegram/{telegram_login}/users", rpc: "GetUserByTelegram"},
+	{method: "DELETE", path: "/v1/users/{user_id}", rpc: "DeleteUser"},
+	{method: "GET", path: "/v1/users/{user_id}/admin", rpc: "IsAdmin"},
+	{method: "POST", path: "/v1/users/{user_id}/admin", rpc: "MakeAdmin"},
+	{method: "DELETE", path: "/v1/users/{user_id}/admin", rpc: "RevokeAdmin"},
+	{method: "PUT", path: "/v1/users/{user_id}/password", rpc: "ChangePassword", body: true},
+	{method: "POST", path: "/v1/users/{user_id}/disable", rpc: "DisableUser"},
+	{method: "POST", path: "/v1/users/{user_id}/enable", rpc: "EnableUser"},
+	{method: "GET", path: "/v1/users/{user_id}/jwt", rpc: "GetJWT"},
+	{method: "DELETE", path: "/v1/users/{user_id}/jwt", rpc: "DeleteJWT"},
+	{method: "POST", path: "/v1/tokens/revoke", rpc: "RevokeToken", body: true},
+	{method: "GET", path: "/v1/users/{user_id}/export", rpc: "ExportUserData"},
+	{method: "POST", path: "/v1/users/{user_id}/erase", rpc: "EraseUserData", body: true},
+	{method: "GET", path: "/v1/audit", rpc: "QueryAuditLog"},
+	{method: "GET", path: "/v1/audit/verify", rpc: "VerifyAuditLog"},
+	{method: "POST", path: "/v1/webhooks", rpc: "RegisterWebhook", body: true},
+	{method: "GET", path: "/v1/webhooks", rpc: "ListWebhooks"},
+	{method: "DELETE", path: "/v1/webhooks/{webhook_id}", rpc: "DeleteWebhook"},
+	{method: "GET", path: "/v1/webhooks/deliveries/dead", rpc: "ListDeadDeliveries"},
+	{method: "POST", path: "/v1/webhooks/deliveries/{delivery_id}/replay", rpc: "ReplayDelivery"},
+}
+
+var pathParamRe = regexp.MustCompile(`\{(\w+)\}`)
+
+func (r route) pattern() string {
+	return r.method + " " + r.path
+}
+
+func (r route) pathParams() []string {
+	matches := pathParamRe.FindAllStringSubmatch(r.path, -1)
+
+	params := make([]string, 0, len(matches))
+	for _, m := range matches {
+		params = append(params, m[1])
+	}
+
+	return params
+}
+
+func (r route) fullMethod() string {
+	return "/" + ssov1.Auth_ServiceDesc.ServiceName + "/" + r.rpc
+}
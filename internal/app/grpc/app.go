@@ -6,23 +6,39 @@ import (
 	"log/slog"
 	"net"
 
+	grpcprom "github.com/grpc-ecosystem/go-grpc-middleware/providers/prometheus"
 	"github.com/grpc-ecosystem/go-grpc-middleware/v2/interceptors/logging"
 	"github.com/grpc-ecosystem/go-grpc-middleware/v2/interceptors/recovery"
+	ssov1 "github.com/j0n1que/sso-protos/gen/go"
+	"github.com/j0n1que/sso-service/internal/config"
 	authgrpc "github.com/j0n1que/sso-service/internal/grpc/auth"
-	"github.com/j0n1que/sso-service/internal/storage/mongo"
-	"github.com/j0n1que/sso-service/internal/storage/redis"
+	"github.com/j0n1que/sso-service/internal/lib/metrics"
+	"github.com/j0n1que/sso-service/internal/lib/tlsconfig"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc/filters"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/keepalive"
+	"google.golang.org/grpc/reflection"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
 )
 
 type App struct {
-	log        *slog.Logger
-	gRPCServer *grpc.Server
-	port       int
+	log            *slog.Logger
+	gRPCServer     *grpc.Server
+	port           int
+	authServer     ssov1.AuthServer
+	interceptor    grpc.UnaryServerInterceptor
+	deadlines      *DeadlineInterceptor
+	rateLimiter    *RateLimiter
+	authMiddleware *AuthMiddleware
 }
 
-func New(log *slog.Logger, port int, authService authgrpc.Auth, tokenStorage *redis.TokenStorage, userStorage *mongo.UserDAO) *App {
+func New(log *slog.Logger, cfg config.GRPCConfig, authService authgrpc.Auth, webhooksService authgrpc.Webhooks, healthServer *health.Server, tokenStorage TokenProvider, userStorage UserProvider, validator TokenValidator) *App {
 	loggingOpts := []logging.Option{
 		logging.WithLogOnEvents(
 			logging.PayloadReceived, logging.PayloadSent,
@@ -37,23 +53,109 @@ func New(log *slog.Logger, port int, authService authgrpc.Auth, tokenStorage *re
 		}),
 	}
 
-	authMiddleware := NewAuthMiddleware(tokenStorage, userStorage)
+	deadlines := NewDeadlineInterceptor(cfg.Timeout, cfg.MethodTimeouts)
 
-	gRPCServer := grpc.NewServer(grpc.ChainUnaryInterceptor(
+	rateLimiter := NewRateLimiter(cfg.RateLimit)
+
+	authMiddleware := NewAuthMiddleware(tokenStorage, userStorage, validator, cfg.TLS.Principals)
+	authMiddleware.SetMethodAccess(cfg.MethodAccess)
+
+	srvMetrics := grpcprom.NewServerMetrics(grpcprom.WithServerHandlingTimeHistogram())
+	metrics.Registry.MustRegister(srvMetrics)
+
+	interceptors := []grpc.UnaryServerInterceptor{
+		RequestIDInterceptor,
+		srvMetrics.UnaryServerInterceptor(),
+		deadlines.UnaryInterceptor,
+		CallerInterceptor,
+		rateLimiter.UnaryInterceptor,
 		authMiddleware.UnaryInterceptor,
 		recovery.UnaryServerInterceptor(recoveryOpts...),
 		logging.UnaryServerInterceptor(InterceptorLogger(log), loggingOpts...),
-	))
+	}
+
+	// streams are long-lived, so they get no default deadline
+	streamInterceptors := []grpc.StreamServerInterceptor{
+		RequestIDStreamInterceptor,
+		srvMetrics.StreamServerInterceptor(),
+		CallerStreamInterceptor,
+		rateLimiter.StreamInterceptor,
+		authMiddleware.StreamInterceptor,
+		recovery.StreamServerInterceptor(recoveryOpts...),
+		logging.StreamServerInterceptor(InterceptorLogger(log), loggingOpts...),
+	}
+
+	serverOpts := []grpc.ServerOption{
+		grpc.StatsHandler(otelgrpc.NewServerHandler(
+			otelgrpc.WithFilter(filters.Not(filters.HealthCheck())),
+		)),
+		grpc.ChainUnaryInterceptor(interceptors...),
+		grpc.ChainStreamInterceptor(streamInterceptors...),
+		grpc.MaxRecvMsgSize(cfg.MaxRecvMsgSize),
+		grpc.MaxSendMsgSize(cfg.MaxSendMsgSize),
+		grpc.KeepaliveParams(keepalive.ServerParameters{
+			Time:              cfg.Keepalive.Time,
+			Timeout:           cfg.Keepalive.Timeout,
+			MaxConnectionIdle: cfg.Keepalive.MaxConnectionIdle,
+			MaxConnectionAge:  cfg.Keepalive.MaxConnectionAge,
+		}),
+		grpc.KeepaliveEnforcementPolicy(keepalive.EnforcementPolicy{
+			MinTime:             cfg.Keepalive.MinTime,
+			PermitWithoutStream: cfg.Keepalive.PermitWithoutStream,
+		}),
+	}
+
+	if cfg.TLS.Enabled() {
+		reloader, err := tlsconfig.New(log, cfg.TLS)
+		if err != nil {
+			panic("failed to load TLS certificates: " + err.Error())
+		}
+		serverOpts = append(serverOpts, grpc.Creds(credentials.NewTLS(reloader.Config())))
+	} else {
+		log.Warn("gRPC listener is not using TLS, credentials are sent in plaintext")
+	}
+
+	gRPCServer := grpc.NewServer(serverOpts...)
+
+	authServer := registerServices(gRPCServer, authService, webhooksService, healthServer, cfg.Reflection)
+
+	authMiddleware.MustCoverAll(gRPCServer.GetServiceInfo())
 
-	authgrpc.Register(gRPCServer, authService)
+	srvMetrics.InitializeMetrics(gRPCServer)
 
 	return &App{
-		log:        log,
-		gRPCServer: gRPCServer,
-		port:       port,
+		log:            log,
+		gRPCServer:     gRPCServer,
+		port:           cfg.Port,
+		authServer:     authServer,
+		interceptor:    chainUnaryInterceptors(interceptors),
+		deadlines:      deadlines,
+		rateLimiter:    rateLimiter,
+		authMiddleware: authMiddleware,
 	}
 }
 
+// registerServices registers every service the server exposes.
+func registerServices(gRPCServer *grpc.Server, authService authgrpc.Auth, webhooksService authgrpc.Webhooks, healthServer *health.Server, withReflection bool) ssov1.AuthServer {
+	authServer := authgrpc.Register(gRPCServer, authService, webhooksService)
+	healthpb.RegisterHealthServer(gRPCServer, healthServer)
+
+	if withReflection {
+		reflection.Register(gRPCServer)
+	}
+
+	return authServer
+}
+
+// Reload applies the settings of cfg that can change while serving: call
+// timeouts, rate limit, method access and machine principals.
+func (a *App) Reload(cfg config.GRPCConfig) {
+	a.deadlines.SetTimeouts(cfg.Timeout, cfg.MethodTimeouts)
+	a.rateLimiter.SetLimit(cfg.RateLimit)
+	a.authMiddleware.SetMethodAccess(cfg.MethodAccess)
+	a.authMiddleware.SetPrincipals(cfg.TLS.Principals)
+}
+
 func (a *App) MustRun() {
 	if err := a.Run(); err != nil {
 		panic(err)
@@ -90,8 +192,45 @@ func (a *App) Stop() {
 	a.gRPCServer.GracefulStop()
 }
 
+// Invoke calls the Auth method with the given name in-process, running it
+// through the same interceptor chain as calls received over the network. dec
+// fills in the request message.
+func (a *App) Invoke(ctx context.Context, method string, dec func(interface{}) error) (interface{}, error) {
+	for _, desc := range ssov1.Auth_ServiceDesc.Methods {
+		if desc.MethodName == method {
+			return desc.Handler(a.authServer, ctx, dec, a.interceptor)
+		}
+	}
+
+	return nil, status.Errorf(codes.Unimplemented, "unknown method %s", method)
+}
+
+// chainUnaryInterceptors composes interceptors the way grpc.ChainUnaryInterceptor
+// does, the first one being the outermost.
+func chainUnaryInterceptors(interceptors []grpc.UnaryServerInterceptor) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		next := handler
+		for i := len(interceptors) - 1; i >= 0; i-- {
+			interceptor, inner := interceptors[i], next
+			next = func(ctx context.Context, req interface{}) (interface{}, error) {
+				return interceptor(ctx, req, info, inner)
+			}
+		}
+
+		return next(ctx, req)
+	}
+}
+
+// InterceptorLogger adapts l to the logging interceptor, masking the
+// sensitive fields of the logged payloads.
 func InterceptorLogger(l *slog.Logger) logging.Logger {
 	return logging.LoggerFunc(func(ctx context.Context, lvl logging.Level, msg string, fields ...any) {
+		for i, field := range fields {
+			if payload, ok := field.(proto.Message); ok {
+				fields[i] = redactPayload(payload)
+			}
+		}
+
 		l.Log(ctx, slog.Level(lvl), msg, fields...)
 	})
 }
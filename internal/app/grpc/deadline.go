@@ -0,0 +1,64 @@
+package grpcapp
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// DeadlineInterceptor bounds every call by the configured timeout of its
+// method. A deadline set by the client is kept when it is earlier.
+type DeadlineInterceptor struct {
+	timeouts atomic.Pointer[timeouts]
+}
+
+type timeouts struct {
+	timeout        time.Duration
+	methodTimeouts map[string]time.Duration
+}
+
+func NewDeadlineInterceptor(timeout time.Duration, methodTimeouts map[string]time.Duration) *DeadlineInterceptor {
+	d := &DeadlineInterceptor{}
+	d.SetTimeouts(timeout, methodTimeouts)
+
+	return d
+}
+
+// SetTimeouts replaces the timeouts applied to the calls started from now on.
+func (d *DeadlineInterceptor) SetTimeouts(timeout time.Duration, methodTimeouts map[string]time.Duration) {
+	d.timeouts.Store(&timeouts{timeout: timeout, methodTimeouts: methodTimeouts})
+}
+
+func (d *DeadlineInterceptor) UnaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	t := d.timeouts.Load()
+
+	timeout, ok := t.methodTimeouts[info.FullMethod]
+	if !ok {
+		timeout = t.timeout
+	}
+
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	resp, err := handler(ctx, req)
+	if err != nil {
+		// handlers report storage failures as internal errors, surface the
+		// real cause when the call ran out of time
+		switch {
+		case errors.Is(ctx.Err(), context.DeadlineExceeded):
+			return nil, status.Error(codes.DeadlineExceeded, "deadline exceeded")
+		case errors.Is(ctx.Err(), context.Canceled):
+			return nil, status.Error(codes.Canceled, "call canceled")
+		}
+	}
+
+	return resp, err
+}
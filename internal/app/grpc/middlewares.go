@@ -3,33 +3,237 @@ package grpcapp
 import (
 	"context"
 	"errors"
+	"net"
+	"sort"
+	"strings"
+	"sync/atomic"
 
+	middleware "github.com/grpc-ecosystem/go-grpc-middleware/v2"
+	ssov1 "github.com/j0n1que/sso-protos/gen/go"
+	"github.com/j0n1que/sso-service/internal/config"
 	"github.com/j0n1que/sso-service/internal/domain/models"
+	"github.com/j0n1que/sso-service/internal/lib/caller"
+	"github.com/j0n1que/sso-service/internal/lib/jwt"
 	"github.com/j0n1que/sso-service/internal/storage"
-	"github.com/j0n1que/sso-service/internal/storage/mongo"
-	"github.com/j0n1que/sso-service/internal/storage/redis"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
 	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	reflectionv1 "google.golang.org/grpc/reflection/grpc_reflection_v1"
+	reflectionv1alpha "google.golang.org/grpc/reflection/grpc_reflection_v1alpha"
 	"google.golang.org/grpc/status"
 )
 
+// access is the kind of caller a method accepts.
+type access int
+
+const (
+	// accessOpen methods are called without authentication.
+	accessOpen access = iota + 1
+	// accessGuest methods are called by users without a session.
+	accessGuest
+	// accessUser methods are called by any user with a session.
+	accessUser
+	// accessAdmin methods are called by admins with a session.
+	accessAdmin
+)
+
+// methodAccess lists the access of every Auth method. A method missing here
+// is rejected, and the server refuses to start with it.
+var methodAccess = map[string]access{
+	ssov1.Auth_RegisterNewUser_FullMethodName:    accessGuest,
+	ssov1.Auth_AuthorizeUser_FullMethodName:      accessGuest,
+	ssov1.Auth_ChangePassword_FullMethodName:     accessUser,
+	ssov1.Auth_IsAdmin_FullMethodName:            accessAdmin,
+	ssov1.Auth_GetAllUsers_FullMethodName:        accessAdmin,
+	ssov1.Auth_GetUserByTelegram_FullMethodName:  accessAdmin,
+	ssov1.Auth_MakeAdmin_FullMethodName:          accessAdmin,
+	ssov1.Auth_GetJWT_FullMethodName:             accessAdmin,
+	ssov1.Auth_DeleteJWT_FullMethodName:          accessAdmin,
+	ssov1.Auth_RevokeAdmin_FullMethodName:        accessAdmin,
+	ssov1.Auth_DisableUser_FullMethodName:        accessAdmin,
+	ssov1.Auth_EnableUser_FullMethodName:         accessAdmin,
+	ssov1.Auth_DeleteUser_FullMethodName:         accessAdmin,
+	ssov1.Auth_ExportUserData_FullMethodName:     accessAdmin,
+	ssov1.Auth_EraseUserData_FullMethodName:      accessAdmin,
+	ssov1.Auth_QueryAuditLog_FullMethodName:      accessAdmin,
+	ssov1.Auth_VerifyAuditLog_FullMethodName:     accessAdmin,
+	ssov1.Auth_RegisterWebhook_FullMethodName:    accessAdmin,
+	ssov1.Auth_ListWebhooks_FullMethodName:       accessAdmin,
+	ssov1.Auth_DeleteWebhook_FullMethodName:      accessAdmin,
+	ssov1.Auth_ListDeadDeliveries_FullMethodName: accessAdmin,
+	ssov1.Auth_ReplayDelivery_FullMethodName:     accessAdmin,
+	// holding the token is enough to revoke it
+	ssov1.Auth_RevokeToken_FullMethodName: accessOpen,
+}
+
+// serviceAccess applies to every method of the listed services.
+var serviceAccess = map[string]access{
+	// probes come from the orchestrator, not from users
+	healthpb.Health_ServiceDesc.ServiceName: accessOpen,
+	// reflection is only registered where it is enabled
+	reflectionv1.ServerReflection_ServiceDesc.ServiceName:      accessOpen,
+	reflectionv1alpha.ServerReflection_ServiceDesc.ServiceName: accessOpen,
+}
+
+// accessLevels maps the access levels of the config to their access.
+var accessLevels = map[string]access{
+	config.AccessOpen:  accessOpen,
+	config.AccessGuest: accessGuest,
+	config.AccessUser:  accessUser,
+	config.AccessAdmin: accessAdmin,
+}
+
+func methodAccessOf(fullMethod string) (access, bool) {
+	if a, ok := methodAccess[fullMethod]; ok {
+		return a, true
+	}
+
+	service, _, _ := strings.Cut(strings.TrimPrefix(fullMethod, "/"), "/")
+	a, ok := serviceAccess[service]
+
+	return a, ok
+}
+
+type UserProvider interface {
+	Access(ctx context.Context, userID int64) (models.UserAccess, error)
+	AccessByTelegram(ctx context.Context, telegramLogin string) ([]models.UserAccess, error)
+}
+
+type TokenProvider interface {
+	JWT(ctx context.Context, userID int64) (string, error)
+}
+
+type TokenValidator interface {
+	ValidateToken(ctx context.Context, token string) (jwt.Claims, error)
+}
+
 type AuthMiddleware struct {
-	tokenStorage *redis.TokenStorage
-	userStorage  *mongo.UserDAO
+	tokenStorage TokenProvider
+	userStorage  UserProvider
+	validator    TokenValidator
+	principals   atomic.Pointer[map[string]map[string]bool]
+	overrides    atomic.Pointer[map[string]access]
 }
 
-func NewAuthMiddleware(tokenStorage *redis.TokenStorage, userStorage *mongo.UserDAO) *AuthMiddleware {
-	return &AuthMiddleware{
+func NewAuthMiddleware(tokenStorage TokenProvider, userStorage UserProvider, validator TokenValidator, principals []config.PrincipalConfig) *AuthMiddleware {
+	am := &AuthMiddleware{
 		tokenStorage: tokenStorage,
 		userStorage:  userStorage,
+		validator:    validator,
 	}
+	am.SetPrincipals(principals)
+
+	return am
+}
+
+// SetPrincipals replaces the machine principals and the methods they may call.
+func (am *AuthMiddleware) SetPrincipals(principals []config.PrincipalConfig) {
+	allowed := make(map[string]map[string]bool, len(principals))
+	for _, p := range principals {
+		methods := make(map[string]bool, len(p.Methods))
+		for _, m := range p.Methods {
+			methods[m] = true
+		}
+		allowed[p.Identity] = methods
+	}
+
+	am.principals.Store(&allowed)
+}
+
+// SetMethodAccess replaces the access overriding the built-in one of the
+// given full method names. Unknown levels are skipped, config validation
+// rejects them.
+func (am *AuthMiddleware) SetMethodAccess(methods map[string]string) {
+	overrides := make(map[string]access, len(methods))
+	for method, level := range methods {
+		if a, ok := accessLevels[level]; ok {
+			overrides[method] = a
+		}
+	}
+
+	am.overrides.Store(&overrides)
+}
+
+// accessOf returns the access of fullMethod, preferring the overrides.
+func (am *AuthMiddleware) accessOf(fullMethod string) (access, bool) {
+	if overrides := am.overrides.Load(); overrides != nil {
+		if a, ok := (*overrides)[fullMethod]; ok {
+			return a, true
+		}
+	}
+
+	return methodAccessOf(fullMethod)
+}
+
+// MustCoverAll panics if a method registered on the server has no access
+// rule, so that no method can be served without going through the check.
+func (am *AuthMiddleware) MustCoverAll(services map[string]grpc.ServiceInfo) {
+	if missing := uncovered(services); len(missing) > 0 {
+		panic("no access rule for gRPC methods: " + strings.Join(missing, ", "))
+	}
+}
+
+// uncovered returns the sorted full names of the methods of services that
+// have no access rule.
+func uncovered(services map[string]grpc.ServiceInfo) []string {
+	var missing []string
+
+	for service, info := range services {
+		for _, method := range info.Methods {
+			fullMethod := "/" + service + "/" + method.Name
+			if _, ok := methodAccessOf(fullMethod); !ok {
+				missing = append(missing, fullMethod)
+			}
+		}
+	}
+
+	sort.Strings(missing)
+
+	return missing
 }
 
 func (am *AuthMiddleware) UnaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
-	publicMethods := map[string]bool{
-		"/Auth/RegisterNewUser": true,
-		"/Auth/AuthorizeUser":   true,
+	ctx, err := am.authorize(ctx, info.FullMethod)
+	if err != nil {
+		return nil, err
+	}
+
+	return handler(ctx, req)
+}
+
+func (am *AuthMiddleware) StreamInterceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx, err := am.authorize(ss.Context(), info.FullMethod)
+	if err != nil {
+		return err
+	}
+
+	wrapped := middleware.WrapServerStream(ss)
+	wrapped.WrappedContext = ctx
+
+	return handler(srv, wrapped)
+}
+
+// authorize checks that the caller may call fullMethod and returns the
+// context carrying its identity.
+func (am *AuthMiddleware) authorize(ctx context.Context, fullMethod string) (context.Context, error) {
+	level, ok := am.accessOf(fullMethod)
+	if !ok {
+		return nil, status.Errorf(codes.PermissionDenied, "access denied")
+	}
+
+	if level == accessOpen {
+		return ctx, nil
+	}
+
+	if principal, ok := am.machinePrincipal(ctx); ok {
+		methods := (*am.principals.Load())[principal]
+		if !methods["*"] && !methods[fullMethod] {
+			return nil, status.Errorf(codes.PermissionDenied, "access denied")
+		}
+		return caller.WithPrincipal(ctx, principal), nil
 	}
 
 	md, flag := metadata.FromIncomingContext(ctx)
@@ -37,49 +241,148 @@ func (am *AuthMiddleware) UnaryInterceptor(ctx context.Context, req interface{},
 		return nil, status.Errorf(codes.Unauthenticated, "missing metadata")
 	}
 
+	if authorization := md.Get("authorization"); len(authorization) > 0 {
+		token, ok := strings.CutPrefix(authorization[0], "Bearer ")
+		if !ok {
+			return nil, status.Errorf(codes.Unauthenticated, "unsupported authorization scheme")
+		}
+
+		claims, err := am.validator.ValidateToken(ctx, token)
+		if err != nil {
+			return nil, status.Errorf(codes.Unauthenticated, "invalid token")
+		}
+
+		return am.authorizeUser(ctx, level, claims.UserID)
+	}
+
 	telegramLogin := md.Get("telegramLogin")
 	if len(telegramLogin) == 0 {
 		return nil, status.Errorf(codes.Unauthenticated, "missing login in header")
 	}
 
-	users, err := am.userStorage.GetUserByTelegram(ctx, telegramLogin[0])
+	users, err := am.userStorage.AccessByTelegram(ctx, telegramLogin[0])
 	if err != nil {
-		if errors.Is(err, storage.ErrUserNotFound) && info.FullMethod == "/Auth/RegisterNewUser" {
-			return handler(ctx, req)
+		if errors.Is(err, storage.ErrUserNotFound) && fullMethod == ssov1.Auth_RegisterNewUser_FullMethodName {
+			return ctx, nil
 		}
 		return nil, status.Errorf(codes.Unauthenticated, "user with such telegram login not found: %v", err)
 	}
 
 	userID := am.findSesion(ctx, users)
 	if userID == -1 {
-		if publicMethods[info.FullMethod] {
-			return handler(ctx, req)
+		if level == accessGuest {
+			return ctx, nil
 		}
 		return nil, status.Errorf(codes.Unauthenticated, "missing user")
 	}
 
-	if publicMethods[info.FullMethod] {
+	return am.authorizeUser(ctx, level, userID)
+}
+
+// authorizeUser checks that the authenticated user may call a method with the
+// given access and returns the context carrying the user ID. The user is
+// looked up on every call since bearer tokens outlive the user being disabled
+// or deleted.
+func (am *AuthMiddleware) authorizeUser(ctx context.Context, level access, userID int64) (context.Context, error) {
+	if level == accessGuest {
 		return nil, status.Errorf(codes.PermissionDenied, "access denied for authenticated users")
 	}
 
-	isAdmin, err := am.userStorage.IsAdmin(ctx, userID)
+	user, err := am.userStorage.Access(ctx, userID)
 	if err != nil {
-		return nil, status.Errorf(codes.Internal, "error checking admin status: %v", err)
+		if errors.Is(err, storage.ErrUserNotFound) {
+			return nil, status.Errorf(codes.Unauthenticated, "missing user")
+		}
+		return nil, status.Errorf(codes.Internal, "error checking user status: %v", err)
 	}
 
-	if isAdmin {
-		return handler(ctx, req)
+	if !user.Active() {
+		return nil, status.Errorf(codes.Unauthenticated, "user is disabled")
 	}
 
-	if info.FullMethod == "/Auth/ChangePassword" {
-		return handler(ctx, req)
+	ctx = caller.WithUserID(ctx, userID)
+
+	if level == accessUser {
+		return ctx, nil
+	}
+
+	if !user.IsAdmin {
+		return nil, status.Errorf(codes.PermissionDenied, "access denied")
 	}
 
-	return nil, status.Errorf(codes.PermissionDenied, "access denied")
+	return ctx, nil
 }
 
-func (am *AuthMiddleware) findSesion(ctx context.Context, users []models.User) int64 {
+// CallerInterceptor stores the client address and user agent of the call in
+// the context so that services can record them.
+func CallerInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	return handler(withCaller(ctx), req)
+}
+
+func CallerStreamInterceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	wrapped := middleware.WrapServerStream(ss)
+	wrapped.WrappedContext = withCaller(ss.Context())
+
+	return handler(srv, wrapped)
+}
+
+func withCaller(ctx context.Context) context.Context {
+	var ci caller.Info
+
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		ci.IP = p.Addr.String()
+		if host, _, err := net.SplitHostPort(ci.IP); err == nil {
+			ci.IP = host
+		}
+	}
+
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if ua := md.Get("user-agent"); len(ua) > 0 {
+			ci.UserAgent = ua[0]
+		}
+	}
+
+	return caller.WithInfo(ctx, ci)
+}
+
+// machinePrincipal returns the configured principal matching the verified
+// client certificate of the call, if any.
+func (am *AuthMiddleware) machinePrincipal(ctx context.Context) (string, bool) {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return "", false
+	}
+
+	tlsInfo, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok || len(tlsInfo.State.VerifiedChains) == 0 || len(tlsInfo.State.VerifiedChains[0]) == 0 {
+		return "", false
+	}
+
+	leaf := tlsInfo.State.VerifiedChains[0][0]
+
+	identities := make([]string, 0, len(leaf.URIs)+len(leaf.DNSNames)+1)
+	for _, uri := range leaf.URIs {
+		identities = append(identities, uri.String())
+	}
+	identities = append(identities, leaf.DNSNames...)
+	identities = append(identities, leaf.Subject.CommonName)
+
+	principals := *am.principals.Load()
+
+	for _, identity := range identities {
+		if _, ok := principals[identity]; ok && identity != "" {
+			return identity, true
+		}
+	}
+
+	return "", false
+}
+
+func (am *AuthMiddleware) findSesion(ctx context.Context, users []models.UserAccess) int64 {
 	for i := range users {
+		if !users[i].Active() {
+			continue
+		}
 		uid := users[i].ID
 		_, err := am.tokenStorage.JWT(ctx, uid)
 		if err == nil {
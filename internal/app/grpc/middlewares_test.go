@@ -0,0 +1,139 @@
+package grpcapp
+
+import (
+	"context"
+	"testing"
+
+	ssov1 "github.com/j0n1que/sso-protos/gen/go"
+	"github.com/j0n1que/sso-service/internal/config"
+	"github.com/j0n1que/sso-service/internal/domain/models"
+	"github.com/j0n1que/sso-service/internal/lib/jwt"
+	"github.com/j0n1que/sso-service/internal/storage"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+type fakeUsers map[int64]models.UserAccess
+
+func (u fakeUsers) Access(ctx context.Context, userID int64) (models.UserAccess, error) {
+	user, ok := u[userID]
+	if !ok {
+		return models.UserAccess{}, storage.ErrUserNotFound
+	}
+
+	return user, nil
+}
+
+func (u fakeUsers) AccessByTelegram(ctx context.Context, telegramLogin string) ([]models.UserAccess, error) {
+	return nil, storage.ErrUserNotFound
+}
+
+// fakeValidator accepts any token as one of the user with the ID in userID.
+type fakeValidator struct {
+	userID int64
+}
+
+func (v fakeValidator) ValidateToken(ctx context.Context, token string) (jwt.Claims, error) {
+	return jwt.Claims{UserID: v.userID}, nil
+}
+
+func TestAuthorizeBearer(t *testing.T) {
+	tests := []struct {
+		name   string
+		user   models.UserAccess
+		method string
+		want   codes.Code
+	}{
+		{
+			name:   "active user",
+			user:   models.UserAccess{ID: 1, Status: models.UserStatusActive},
+			method: ssov1.Auth_ChangePassword_FullMethodName,
+			want:   codes.OK,
+		},
+		{
+			name:   "active admin",
+			user:   models.UserAccess{ID: 1, Status: models.UserStatusActive, IsAdmin: true},
+			method: ssov1.Auth_DisableUser_FullMethodName,
+			want:   codes.OK,
+		},
+		{
+			name:   "user calling admin method",
+			user:   models.UserAccess{ID: 1, Status: models.UserStatusActive},
+			method: ssov1.Auth_DisableUser_FullMethodName,
+			want:   codes.PermissionDenied,
+		},
+		{
+			name:   "disabled user",
+			user:   models.UserAccess{ID: 1, Status: models.UserStatusDisabled},
+			method: ssov1.Auth_ChangePassword_FullMethodName,
+			want:   codes.Unauthenticated,
+		},
+		{
+			name:   "disabled admin",
+			user:   models.UserAccess{ID: 1, Status: models.UserStatusDisabled, IsAdmin: true},
+			method: ssov1.Auth_DisableUser_FullMethodName,
+			want:   codes.Unauthenticated,
+		},
+		{
+			name:   "deleted admin",
+			user:   models.UserAccess{ID: 1, Status: models.UserStatusDeleted, IsAdmin: true},
+			method: ssov1.Auth_DisableUser_FullMethodName,
+			want:   codes.Unauthenticated,
+		},
+		{
+			name:   "erased user",
+			user:   models.UserAccess{ID: 2, Status: models.UserStatusActive},
+			method: ssov1.Auth_ChangePassword_FullMethodName,
+			want:   codes.Unauthenticated,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			users := fakeUsers{tt.user.ID: tt.user}
+			am := NewAuthMiddleware(nil, users, fakeValidator{userID: 1}, nil)
+
+			ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Bearer token"))
+
+			_, err := am.authorize(ctx, tt.method)
+			if got := status.Code(err); got != tt.want {
+				t.Errorf("authorize() code = %v, want %v (error %v)", got, tt.want, err)
+			}
+		})
+	}
+}
+
+func TestMethodAccessOverride(t *testing.T) {
+	users := fakeUsers{1: {ID: 1, Status: models.UserStatusActive}}
+	am := NewAuthMiddleware(nil, users, fakeValidator{userID: 1}, nil)
+
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Bearer token"))
+	method := ssov1.Auth_GetAllUsers_FullMethodName
+
+	if _, err := am.authorize(ctx, method); status.Code(err) != codes.PermissionDenied {
+		t.Fatalf("authorize() error = %v, want PermissionDenied before the override", err)
+	}
+
+	am.SetMethodAccess(map[string]string{method: config.AccessUser})
+
+	if _, err := am.authorize(ctx, method); err != nil {
+		t.Errorf("authorize() error = %v, want nil after the override", err)
+	}
+}
+
+func TestEveryMethodHasAccessRule(t *testing.T) {
+	gRPCServer := grpc.NewServer()
+	registerServices(gRPCServer, nil, nil, health.NewServer(), true)
+
+	services := gRPCServer.GetServiceInfo()
+	if len(services) == 0 {
+		t.Fatal("no services registered")
+	}
+
+	if missing := uncovered(services); len(missing) > 0 {
+		t.Errorf("methods without an access rule: %v", missing)
+	}
+}
@@ -0,0 +1,89 @@
+package grpcapp
+
+import (
+	ssov1 "github.com/j0n1que/sso-protos/gen/go"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+const redacted = "[REDACTED]"
+
+// sensitiveFields are the names of the message fields never written to the
+// logs, wherever they are nested.
+var sensitiveFields = map[protoreflect.Name]bool{
+	"password":     true,
+	"new_password": true,
+	"token":        true,
+	"secret":       true,
+}
+
+// sensitiveMessages are the messages whose listed fields are never written
+// to the logs, for fields whose name is too generic to mask everywhere. The
+// user data export holds the whole profile of a user.
+var sensitiveMessages = map[protoreflect.FullName]map[protoreflect.Name]bool{
+	(&ssov1.ExportUserDataResponse{}).ProtoReflect().Descriptor().FullName(): {"data": true},
+}
+
+// loggedPayload renders a logged message as JSON, in both the text and JSON
+// log formats, with its sensitive fields masked.
+type loggedPayload struct {
+	msg proto.Message
+}
+
+func redactPayload(msg proto.Message) loggedPayload {
+	msg = proto.Clone(msg)
+	redactMessage(msg.ProtoReflect())
+
+	return loggedPayload{msg: msg}
+}
+
+func (p loggedPayload) MarshalJSON() ([]byte, error) {
+	return protojson.Marshal(p.msg)
+}
+
+func (p loggedPayload) String() string {
+	data, err := p.MarshalJSON()
+	if err != nil {
+		return err.Error()
+	}
+
+	return string(data)
+}
+
+func redactMessage(m protoreflect.Message) {
+	var masked []protoreflect.FieldDescriptor
+
+	messageFields := sensitiveMessages[m.Descriptor().FullName()]
+
+	m.Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		switch {
+		case (sensitiveFields[fd.Name()] || messageFields[fd.Name()]) && !fd.IsList() && !fd.IsMap() &&
+			(fd.Kind() == protoreflect.StringKind || fd.Kind() == protoreflect.BytesKind):
+			masked = append(masked, fd)
+		case fd.IsMap():
+			if fd.MapValue().Message() != nil {
+				v.Map().Range(func(_ protoreflect.MapKey, v protoreflect.Value) bool {
+					redactMessage(v.Message())
+					return true
+				})
+			}
+		case fd.Message() == nil:
+		case fd.IsList():
+			for i := 0; i < v.List().Len(); i++ {
+				redactMessage(v.List().Get(i).Message())
+			}
+		default:
+			redactMessage(v.Message())
+		}
+		return true
+	})
+
+	for _, fd := range masked {
+		if fd.Kind() == protoreflect.BytesKind {
+			m.Set(fd, protoreflect.ValueOfBytes([]byte(redacted)))
+			continue
+		}
+		m.Set(fd, protoreflect.ValueOfString(redacted))
+	}
+}
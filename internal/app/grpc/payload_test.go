@@ -0,0 +1,71 @@
+package grpcapp
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	ssov1 "github.com/j0n1que/sso-protos/gen/go"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestRedactPayload(t *testing.T) {
+	tests := []struct {
+		name   string
+		msg    proto.Message
+		secret string
+		// masked is how the mask shows, redacted unless set
+		masked string
+		kept   string
+	}{
+		{
+			name:   "password",
+			msg:    &ssov1.RegisterRequest{Login: "bob", Password: "hunter2hunter2"},
+			secret: "hunter2hunter2",
+			kept:   "bob",
+		},
+		{
+			name:   "token",
+			msg:    &ssov1.AuthorizeResponse{Token: "eyJhbGciOiJIUzI1NiJ9.e30.sig"},
+			secret: "eyJhbGciOiJIUzI1NiJ9",
+		},
+		{
+			name:   "nested password",
+			msg:    &ssov1.ListOfUsers{Users: []*ssov1.User{{Login: "bob", Password: "hunter2hunter2"}}},
+			secret: "hunter2hunter2",
+			kept:   "bob",
+		},
+		{
+			name: "user data export",
+			msg:  &ssov1.ExportUserDataResponse{Data: []byte(`{"profile":{"login":"bob"}}`)},
+			// protojson writes bytes in base64
+			secret: base64.StdEncoding.EncodeToString([]byte(`{"profile":{"login":"bob"}}`)),
+			masked: base64.StdEncoding.EncodeToString([]byte(redacted)),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			original := proto.Clone(tt.msg)
+
+			got := redactPayload(tt.msg).String()
+
+			if strings.Contains(got, tt.secret) {
+				t.Errorf("payload %s contains %q", got, tt.secret)
+			}
+			masked := tt.masked
+			if masked == "" {
+				masked = redacted
+			}
+			if !strings.Contains(got, masked) {
+				t.Errorf("payload %s is not marked as redacted", got)
+			}
+			if tt.kept != "" && !strings.Contains(got, tt.kept) {
+				t.Errorf("payload %s lost %q", got, tt.kept)
+			}
+			if !proto.Equal(tt.msg, original) {
+				t.Errorf("redactPayload changed the logged message")
+			}
+		})
+	}
+}
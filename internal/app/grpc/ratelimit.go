@@ -0,0 +1,112 @@
+package grpcapp
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/j0n1que/sso-service/internal/config"
+	"github.com/j0n1que/sso-service/internal/lib/caller"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// sweepInterval is how often the buckets of idle clients are dropped.
+const sweepInterval = time.Minute
+
+// RateLimiter gives every client IP a token bucket, so that no client can
+// flood the service or guess passwords at speed. It runs after the caller
+// is known and before authentication, which failed logins count against.
+type RateLimiter struct {
+	limit atomic.Pointer[config.RateLimitConfig]
+	now   func() time.Time
+
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+func NewRateLimiter(cfg config.RateLimitConfig) *RateLimiter {
+	l := &RateLimiter{
+		now:     time.Now,
+		buckets: make(map[string]*bucket),
+	}
+	l.SetLimit(cfg)
+
+	return l
+}
+
+// SetLimit replaces the limit applied from now on. Clients keep the tokens
+// they have, up to the new burst.
+func (l *RateLimiter) SetLimit(cfg config.RateLimitConfig) {
+	l.limit.Store(&cfg)
+}
+
+func (l *RateLimiter) UnaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	if !l.allow(caller.FromContext(ctx).IP) {
+		return nil, status.Error(codes.ResourceExhausted, "rate limit exceeded")
+	}
+
+	return handler(ctx, req)
+}
+
+func (l *RateLimiter) StreamInterceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if !l.allow(caller.FromContext(ss.Context()).IP) {
+		return status.Error(codes.ResourceExhausted, "rate limit exceeded")
+	}
+
+	return handler(srv, ss)
+}
+
+// allow takes a token from the bucket of client and reports whether there
+// was one.
+func (l *RateLimiter) allow(client string) bool {
+	limit := l.limit.Load()
+	if limit.Rate <= 0 {
+		return true
+	}
+
+	burst := float64(limit.Burst)
+	now := l.now()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if now.Sub(l.lastSweep) >= sweepInterval {
+		l.sweep(now, limit.Rate, burst)
+	}
+
+	b, ok := l.buckets[client]
+	if !ok {
+		b = &bucket{tokens: burst, last: now}
+		l.buckets[client] = b
+	}
+
+	b.tokens = min(burst, b.tokens+now.Sub(b.last).Seconds()*limit.Rate)
+	b.last = now
+
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+
+	return true
+}
+
+// sweep drops the buckets that have refilled, as a new one would be full too.
+func (l *RateLimiter) sweep(now time.Time, rate, burst float64) {
+	for client, b := range l.buckets {
+		if b.tokens+now.Sub(b.last).Seconds()*rate >= burst {
+			delete(l.buckets, client)
+		}
+	}
+
+	l.lastSweep = now
+}
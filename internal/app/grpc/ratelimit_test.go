@@ -0,0 +1,54 @@
+package grpcapp
+
+import (
+	"testing"
+	"time"
+
+	"github.com/j0n1que/sso-service/internal/config"
+)
+
+func TestRateLimiter(t *testing.T) {
+	now := time.Unix(0, 0)
+
+	l := NewRateLimiter(config.RateLimitConfig{Rate: 1, Burst: 2})
+	l.now = func() time.Time { return now }
+
+	for i, want := range []bool{true, true, false} {
+		if got := l.allow("10.0.0.1"); got != want {
+			t.Errorf("call %d allow() = %v, want %v", i+1, got, want)
+		}
+	}
+
+	if !l.allow("10.0.0.2") {
+		t.Error("allow() = false for another client, want its own bucket")
+	}
+
+	now = now.Add(time.Second)
+	if !l.allow("10.0.0.1") {
+		t.Error("allow() = false after a token refilled")
+	}
+	if l.allow("10.0.0.1") {
+		t.Error("allow() = true with the bucket empty again")
+	}
+
+	l.SetLimit(config.RateLimitConfig{})
+	if !l.allow("10.0.0.1") {
+		t.Error("allow() = false with the limit disabled")
+	}
+}
+
+func TestRateLimiterSweepsRefilledBuckets(t *testing.T) {
+	now := time.Unix(0, 0)
+
+	l := NewRateLimiter(config.RateLimitConfig{Rate: 1, Burst: 2})
+	l.now = func() time.Time { return now }
+
+	l.allow("10.0.0.1")
+
+	now = now.Add(sweepInterval)
+	l.allow("10.0.0.2")
+
+	if _, ok := l.buckets["10.0.0.1"]; ok {
+		t.Error("bucket of an idle client kept after the sweep")
+	}
+}
@@ -0,0 +1,43 @@
+package grpcapp
+
+import (
+	"context"
+
+	middleware "github.com/grpc-ecosystem/go-grpc-middleware/v2"
+	"github.com/j0n1que/sso-service/internal/lib/requestid"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// RequestIDInterceptor stores the x-request-id of the call, or a new one when
+// the client sent none, in the context and returns it in the response header.
+// It runs first so that every log line of the call carries the ID.
+func RequestIDInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	ctx, id := withRequestID(ctx)
+
+	// fails for in-process calls from the gateway, which sets the header itself
+	_ = grpc.SetHeader(ctx, metadata.Pairs(requestid.MetadataKey, id))
+
+	return handler(ctx, req)
+}
+
+func RequestIDStreamInterceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx, id := withRequestID(ss.Context())
+
+	_ = ss.SetHeader(metadata.Pairs(requestid.MetadataKey, id))
+
+	wrapped := middleware.WrapServerStream(ss)
+	wrapped.WrappedContext = ctx
+
+	return handler(srv, wrapped)
+}
+
+func withRequestID(ctx context.Context) (context.Context, string) {
+	var id string
+	if ids := metadata.ValueFromIncomingContext(ctx, requestid.MetadataKey); len(ids) > 0 {
+		id = ids[0]
+	}
+	id = requestid.OrNew(id)
+
+	return requestid.With(ctx, id), id
+}
@@ -0,0 +1,228 @@
+package healthapp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// Probe checks a single dependency of the service.
+type Probe struct {
+	Name  string
+	Check func(ctx context.Context) error
+}
+
+type Options struct {
+	Port     int
+	Interval time.Duration
+	Timeout  time.Duration
+	// Services are gRPC services that need every probe to pass to be serving.
+	Services []string
+}
+
+// App runs the dependency probes and publishes their results through the gRPC
+// health service and the /healthz and /readyz HTTP endpoints. Every probe is
+// also exposed as a gRPC health service of its own name.
+type App struct {
+	log        *slog.Logger
+	server     *health.Server
+	probes     []Probe
+	opts       Options
+	httpServer *http.Server
+
+	mu     sync.RWMutex
+	errors map[string]error
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+func New(log *slog.Logger, probes []Probe, opts Options) *App {
+	a := &App{
+		log:    log,
+		server: health.NewServer(),
+		probes: probes,
+		opts:   opts,
+		errors: make(map[string]error, len(probes)),
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+
+	// not ready until the first round of probes passes
+	a.server.SetServingStatus("", healthpb.HealthCheckResponse_NOT_SERVING)
+	for _, service := range opts.Services {
+		a.server.SetServingStatus(service, healthpb.HealthCheckResponse_NOT_SERVING)
+	}
+	for _, probe := range probes {
+		a.server.SetServingStatus(probe.Name, healthpb.HealthCheckResponse_NOT_SERVING)
+		a.errors[probe.Name] = errors.New("not checked yet")
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", a.healthz)
+	mux.HandleFunc("/readyz", a.readyz)
+
+	a.httpServer = &http.Server{
+		Addr:              fmt.Sprintf(":%d", opts.Port),
+		Handler:           mux,
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+
+	return a
+}
+
+// HealthServer returns the gRPC health service to register on the gRPC server.
+func (a *App) HealthServer() *health.Server {
+	return a.server
+}
+
+func (a *App) MustRun() {
+	if err := a.Run(); err != nil {
+		panic(err)
+	}
+}
+
+func (a *App) Run() error {
+	const op = "healthapp.Run"
+
+	log := a.log.With(
+		slog.String("op", op),
+		slog.Int("port", a.opts.Port),
+	)
+
+	go a.check()
+
+	log.Info("health server is running", slog.String("addr", a.httpServer.Addr))
+
+	if err := a.httpServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (a *App) Stop() {
+	const op = "healthapp.Stop"
+
+	a.log.With(slog.String("op", op)).
+		Info("stopping health server", slog.Int("port", a.opts.Port))
+
+	close(a.stop)
+	<-a.done
+
+	// report NOT_SERVING to clients still watching while we shut down
+	a.server.Shutdown()
+
+	ctx, cancel := context.WithTimeout(context.Background(), a.opts.Timeout)
+	defer cancel()
+
+	a.httpServer.Shutdown(ctx)
+}
+
+func (a *App) check() {
+	defer close(a.done)
+
+	ticker := time.NewTicker(a.opts.Interval)
+	defer ticker.Stop()
+
+	for {
+		a.probe()
+
+		select {
+		case <-a.stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (a *App) probe() {
+	const op = "healthapp.probe"
+
+	log := a.log.With(slog.String("op", op))
+
+	ready := true
+
+	for _, probe := range a.probes {
+		ctx, cancel := context.WithTimeout(context.Background(), a.opts.Timeout)
+		err := probe.Check(ctx)
+		cancel()
+
+		a.mu.Lock()
+		prev := a.errors[probe.Name]
+		a.errors[probe.Name] = err
+		a.mu.Unlock()
+
+		status := healthpb.HealthCheckResponse_SERVING
+		if err != nil {
+			status = healthpb.HealthCheckResponse_NOT_SERVING
+			ready = false
+		}
+		a.server.SetServingStatus(probe.Name, status)
+
+		switch {
+		case err != nil && prev == nil:
+			log.Warn("dependency is unavailable", slog.String("probe", probe.Name), slog.String("error", err.Error()))
+		case err == nil && prev != nil:
+			log.Info("dependency is available", slog.String("probe", probe.Name))
+		}
+	}
+
+	status := healthpb.HealthCheckResponse_SERVING
+	if !ready {
+		status = healthpb.HealthCheckResponse_NOT_SERVING
+	}
+	a.server.SetServingStatus("", status)
+	for _, service := range a.opts.Services {
+		a.server.SetServingStatus(service, status)
+	}
+}
+
+// healthz reports that the process is alive, it doesn't depend on probes.
+func (a *App) healthz(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(`{"status":"ok"}`))
+}
+
+// readyz reports whether every dependency probe passed the last check.
+func (a *App) readyz(w http.ResponseWriter, r *http.Request) {
+	a.mu.RLock()
+	checks := make(map[string]string, len(a.errors))
+	ready := true
+	for name, err := range a.errors {
+		if err != nil {
+			checks[name] = err.Error()
+			ready = false
+			continue
+		}
+		checks[name] = "ok"
+	}
+	a.mu.RUnlock()
+
+	resp := struct {
+		Status string            `json:"status"`
+		Checks map[string]string `json:"checks"`
+	}{
+		Status: "ok",
+		Checks: checks,
+	}
+
+	code := http.StatusOK
+	if !ready {
+		resp.Status = "unavailable"
+		code = http.StatusServiceUnavailable
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(resp)
+}
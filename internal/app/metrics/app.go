@@ -0,0 +1,117 @@
+package metricsapp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/j0n1que/sso-service/internal/lib/metrics"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+type SessionCounter interface {
+	CountSessions(ctx context.Context) (int64, error)
+}
+
+// App serves the Prometheus metrics endpoint and periodically samples the
+// number of active sessions.
+type App struct {
+	log        *slog.Logger
+	sessions   SessionCounter
+	port       int
+	interval   time.Duration
+	httpServer *http.Server
+	stop       chan struct{}
+	done       chan struct{}
+}
+
+func New(log *slog.Logger, sessions SessionCounter, port int, sessionsInterval time.Duration) *App {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(metrics.Registry, promhttp.HandlerOpts{}))
+
+	return &App{
+		log:      log,
+		sessions: sessions,
+		port:     port,
+		interval: sessionsInterval,
+		httpServer: &http.Server{
+			Addr:              fmt.Sprintf(":%d", port),
+			Handler:           mux,
+			ReadHeaderTimeout: 5 * time.Second,
+		},
+		stop: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+}
+
+func (a *App) MustRun() {
+	if err := a.Run(); err != nil {
+		panic(err)
+	}
+}
+
+func (a *App) Run() error {
+	const op = "metricsapp.Run"
+
+	log := a.log.With(
+		slog.String("op", op),
+		slog.Int("port", a.port),
+	)
+
+	go a.sample()
+
+	log.Info("metrics server is running", slog.String("addr", a.httpServer.Addr))
+
+	if err := a.httpServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (a *App) Stop() {
+	const op = "metricsapp.Stop"
+
+	a.log.With(slog.String("op", op)).
+		Info("stopping metrics server", slog.Int("port", a.port))
+
+	close(a.stop)
+	<-a.done
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	a.httpServer.Shutdown(ctx)
+}
+
+func (a *App) sample() {
+	const op = "metricsapp.sample"
+
+	defer close(a.done)
+
+	log := a.log.With(slog.String("op", op))
+
+	ticker := time.NewTicker(a.interval)
+	defer ticker.Stop()
+
+	for {
+		ctx, cancel := context.WithTimeout(context.Background(), a.interval)
+		count, err := a.sessions.CountSessions(ctx)
+		cancel()
+
+		if err != nil {
+			log.Warn("failed to count active sessions", slog.String("error", err.Error()))
+		} else {
+			metrics.ActiveSessions.Set(float64(count))
+		}
+
+		select {
+		case <-a.stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package outboxapp
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/j0n1que/sso-service/internal/domain/models"
+)
+
+const batchSize = 100
+
+type Outbox interface {
+	Pending(ctx context.Context, limit int64) ([]models.Event, error)
+	MarkPublished(ctx context.Context, eventID string) error
+	MarkFailed(ctx context.Context, eventID string, reason string) error
+}
+
+type Publisher interface {
+	Publish(ctx context.Context, event models.Event) error
+}
+
+// App relays events from the outbox to the publishers. An event stays in the
+// outbox until every publisher accepts it, so events survive publisher outages
+// and restarts. Events may be delivered more than once.
+type App struct {
+	log        *slog.Logger
+	outbox     Outbox
+	publishers []Publisher
+	interval   time.Duration
+	stop       chan struct{}
+	done       chan struct{}
+}
+
+func New(log *slog.Logger, outbox Outbox, publishers []Publisher, interval time.Duration) *App {
+	return &App{
+		log:        log,
+		outbox:     outbox,
+		publishers: publishers,
+		interval:   interval,
+		stop:       make(chan struct{}),
+		done:       make(chan struct{}),
+	}
+}
+
+func (a *App) Run() {
+	const op = "outboxapp.Run"
+
+	defer close(a.done)
+
+	log := a.log.With(
+		slog.String("op", op),
+		slog.Duration("interval", a.interval),
+	)
+
+	log.Info("outbox relay is running")
+
+	ticker := time.NewTicker(a.interval)
+	defer ticker.Stop()
+
+	for {
+		a.relay()
+
+		select {
+		case <-a.stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (a *App) Stop() {
+	const op = "outboxapp.Stop"
+
+	a.log.With(slog.String("op", op)).Info("stopping outbox relay")
+
+	close(a.stop)
+	<-a.done
+}
+
+func (a *App) relay() {
+	const op = "outboxapp.relay"
+
+	log := a.log.With(slog.String("op", op))
+
+	ctx, cancel := context.WithTimeout(context.Background(), a.interval)
+	defer cancel()
+
+	events, err := a.outbox.Pending(ctx, batchSize)
+	if err != nil {
+		log.Error("failed to get pending events", slog.String("error", err.Error()))
+		return
+	}
+
+	for _, event := range events {
+		if err := a.publish(ctx, event); err != nil {
+			log.Warn("failed to publish event",
+				slog.String("event_id", event.ID),
+				slog.String("type", event.Type),
+				slog.String("error", err.Error()),
+			)
+			if err := a.outbox.MarkFailed(ctx, event.ID, err.Error()); err != nil {
+				log.Error("failed to record publish failure", slog.String("error", err.Error()))
+			}
+
+			// keep the order, the rest of the batch is retried on the next tick
+			return
+		}
+
+		if err := a.outbox.MarkPublished(ctx, event.ID); err != nil {
+			log.Error("failed to mark event as published",
+				slog.String("event_id", event.ID),
+				slog.String("error", err.Error()),
+			)
+			return
+		}
+	}
+}
+
+func (a *App) publish(ctx context.Context, event models.Event) error {
+	for _, publisher := range a.publishers {
+		if err := publisher.Publish(ctx, event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
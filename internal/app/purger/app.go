@@ -0,0 +1,73 @@
+package purgerapp
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+type Purger interface {
+	PurgeDeletedUsers(ctx context.Context) error
+}
+
+// App periodically removes soft deleted users whose retention window has passed.
+type App struct {
+	log      *slog.Logger
+	purger   Purger
+	interval time.Duration
+	stop     chan struct{}
+	done     chan struct{}
+}
+
+func New(log *slog.Logger, purger Purger, interval time.Duration) *App {
+	return &App{
+		log:      log,
+		purger:   purger,
+		interval: interval,
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+}
+
+func (a *App) Run() {
+	const op = "purgerapp.Run"
+
+	defer close(a.done)
+
+	log := a.log.With(
+		slog.String("op", op),
+		slog.Duration("interval", a.interval),
+	)
+
+	log.Info("users purger is running")
+
+	ticker := time.NewTicker(a.interval)
+	defer ticker.Stop()
+
+	for {
+		a.purge()
+
+		select {
+		case <-a.stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (a *App) Stop() {
+	const op = "purgerapp.Stop"
+
+	a.log.With(slog.String("op", op)).Info("stopping users purger")
+
+	close(a.stop)
+	<-a.done
+}
+
+func (a *App) purge() {
+	ctx, cancel := context.WithTimeout(context.Background(), a.interval)
+	defer cancel()
+
+	// errors are already logged by the auth service, the next tick retries
+	_ = a.purger.PurgeDeletedUsers(ctx)
+}
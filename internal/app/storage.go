@@ -0,0 +1,255 @@
+package app
+
+import (
+	"context"
+	"log/slog"
+
+	healthapp "github.com/j0n1que/sso-service/internal/app/health"
+	metricsapp "github.com/j0n1que/sso-service/internal/app/metrics"
+	outboxapp "github.com/j0n1que/sso-service/internal/app/outbox"
+	webhookapp "github.com/j0n1que/sso-service/internal/app/webhook"
+	"github.com/j0n1que/sso-service/internal/config"
+	"github.com/j0n1que/sso-service/internal/services/auth"
+	"github.com/j0n1que/sso-service/internal/services/webhooks"
+	"github.com/j0n1que/sso-service/internal/storage/cache"
+	memstorage "github.com/j0n1que/sso-service/internal/storage/memory"
+	mongodb "github.com/j0n1que/sso-service/internal/storage/mongo"
+	"github.com/j0n1que/sso-service/internal/storage/postgres"
+	"github.com/j0n1que/sso-service/internal/storage/redis"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+)
+
+const (
+	storageMongo    = "mongo"
+	storagePostgres = "postgres"
+	storageMemory   = "memory"
+
+	tokensRedis  = "redis"
+	tokensMemory = "memory"
+
+	cacheMemory = "memory"
+	cacheRedis  = "redis"
+	cacheNone   = "none"
+)
+
+type UserStore interface {
+	auth.UserChanger
+	auth.UserProvider
+}
+
+type OutboxStore interface {
+	auth.EventOutbox
+	outboxapp.Outbox
+}
+
+type WebhookStore interface {
+	webhooks.WebhookStore
+	webhookapp.DeliveryStore
+}
+
+type Transactor interface {
+	auth.Transactor
+	Supported() bool
+}
+
+type TokenStore interface {
+	auth.TokenProvider
+	metricsapp.SessionCounter
+	Ping(ctx context.Context) error
+	Close()
+}
+
+// Storage holds the stores of the configured backend. All of them share one
+// database connection, which Close releases.
+type Storage struct {
+	Users UserStore
+	// Access answers the lookups of the auth interceptor, through the user
+	// cache when one is configured.
+	Access   *cache.Users
+	Audit    auth.AuditLog
+	Outbox   OutboxStore
+	Webhooks WebhookStore
+	Tx       Transactor
+	Probes   []healthapp.Probe
+	close    func(ctx context.Context) error
+}
+
+func (s *Storage) Close(ctx context.Context) error {
+	return s.close(ctx)
+}
+
+func mustStorage(ctx context.Context, log *slog.Logger, cfg *config.Config) *Storage {
+	switch cfg.Storage {
+	case storageMongo:
+		return mustMongoStorage(ctx, log, cfg)
+	case storagePostgres:
+		return mustPostgresStorage(ctx, cfg)
+	case storageMemory:
+		log.Warn("users are kept in memory and are lost on restart")
+		return memoryStorage(cfg)
+	default:
+		panic("unknown storage: " + cfg.Storage)
+	}
+}
+
+func mustMongoStorage(ctx context.Context, log *slog.Logger, cfg *config.Config) *Storage {
+	if cfg.UsersStorage == "" {
+		panic("usersstorage is required for mongo storage")
+	}
+
+	mongoClient, err := mongodb.Connect(ctx, cfg.UsersStorage, cfg.Mongo)
+	if err != nil {
+		panic("no connection to mongodb" + err.Error())
+	}
+
+	db := mongodb.NewDatabase(mongoClient, cfg.Mongo.Database, cfg.Mongo.CollectionPrefix)
+
+	if cfg.MigrateOnStart {
+		if _, err := mongodb.NewMigrator(log, db).Up(ctx, false); err != nil {
+			panic("failed to migrate mongodb" + err.Error())
+		}
+	}
+
+	userDAO := mongodb.New(ctx, db)
+
+	if err := userDAO.EnsureIndexes(ctx); err != nil {
+		panic("failed to set indexation for users database" + err.Error())
+	}
+
+	auditDAO := mongodb.NewAuditDAO(ctx, db, cfg.Audit.HashChain)
+
+	if err := auditDAO.EnsureIndexes(ctx); err != nil {
+		panic("failed to set indexation for audit log" + err.Error())
+	}
+
+	outboxDAO := mongodb.NewOutboxDAO(ctx, db)
+
+	if err := outboxDAO.EnsureIndexes(ctx); err != nil {
+		panic("failed to set indexation for events outbox" + err.Error())
+	}
+
+	webhookDAO := mongodb.NewWebhookDAO(ctx, db)
+
+	if err := webhookDAO.EnsureIndexes(ctx); err != nil {
+		panic("failed to set indexation for webhooks" + err.Error())
+	}
+
+	transactor, err := mongodb.NewTransactor(ctx, mongoClient)
+	if err != nil {
+		panic("failed to check mongodb topology" + err.Error())
+	}
+	if !transactor.Supported() {
+		if !cfg.Mongo.AllowStandalone {
+			panic("mongodb is not a replica set, set mongo.allowstandalone to write events without transactions")
+		}
+		log.Warn("mongodb is not a replica set, events are written to the outbox without transactions")
+	}
+
+	return &Storage{
+		Users:    userDAO,
+		Audit:    auditDAO,
+		Outbox:   outboxDAO,
+		Webhooks: webhookDAO,
+		Tx:       transactor,
+		Probes: []healthapp.Probe{{
+			Name: "mongo",
+			Check: func(ctx context.Context) error {
+				return mongoClient.Ping(ctx, readpref.Primary())
+			},
+		}},
+		close: mongoClient.Disconnect,
+	}
+}
+
+func mustPostgresStorage(ctx context.Context, cfg *config.Config) *Storage {
+	if cfg.Postgres.DSN == "" {
+		panic("postgres.dsn is required for postgres storage")
+	}
+
+	pool, err := postgres.Connect(ctx, cfg.Postgres.DSN, cfg.Postgres.MaxConns)
+	if err != nil {
+		panic("no connection to postgres" + err.Error())
+	}
+
+	if cfg.MigrateOnStart {
+		if err := postgres.Migrate(ctx, pool); err != nil {
+			panic("failed to migrate postgres schema" + err.Error())
+		}
+	}
+
+	return &Storage{
+		Users:    postgres.New(pool),
+		Audit:    postgres.NewAuditDAO(pool, cfg.Audit.HashChain),
+		Outbox:   postgres.NewOutboxDAO(pool),
+		Webhooks: postgres.NewWebhookDAO(pool),
+		Tx:       postgres.NewTransactor(pool),
+		Probes: []healthapp.Probe{{
+			Name:  "postgres",
+			Check: pool.Ping,
+		}},
+		close: func(context.Context) error {
+			pool.Close()
+			return nil
+		},
+	}
+}
+
+func memoryStorage(cfg *config.Config) *Storage {
+	return &Storage{
+		Users:    memstorage.New(),
+		Audit:    memstorage.NewAuditDAO(cfg.Audit.HashChain),
+		Outbox:   memstorage.NewOutboxDAO(),
+		Webhooks: memstorage.NewWebhookDAO(),
+		Tx:       memstorage.NewTransactor(),
+		close: func(context.Context) error {
+			return nil
+		},
+	}
+}
+
+// withUserCache puts the configured cache in front of the user store.
+func withUserCache(ctx context.Context, cfg *config.Config, store *Storage) {
+	var backend cache.Backend
+	switch cfg.UserCache.Type {
+	case cacheNone:
+	case cacheMemory:
+		backend = cache.NewLRU(cfg.UserCache.Size)
+	case cacheRedis:
+		client, err := redis.Connect(ctx, cfg.TokensStorage)
+		if err != nil {
+			panic("no connection to redis for user cache: " + err.Error())
+		}
+		redisCache := cache.NewRedis(client, cfg.TokensStorage.KeyPrefix+"cache:")
+		backend = redisCache
+
+		storeClose := store.close
+		store.close = func(ctx context.Context) error {
+			redisCache.Close()
+			return storeClose(ctx)
+		}
+	default:
+		panic("unknown user cache: " + cfg.UserCache.Type)
+	}
+
+	store.Access = cache.NewUsers(store.Users, backend, cfg.UserCache.TTL)
+	store.Users = store.Access
+}
+
+// mustTokenStore returns the configured token store and the health probes of
+// the services it depends on.
+func mustTokenStore(ctx context.Context, log *slog.Logger, cfg *config.Config) (TokenStore, []healthapp.Probe) {
+	switch cfg.TokensStorage.Type {
+	case tokensRedis:
+		client, err := redis.Connect(ctx, cfg.TokensStorage)
+		if err != nil {
+			panic("no connection to redis: " + err.Error())
+		}
+		tokens := redis.New(client, cfg.TokensStorage.KeyPrefix)
+		return tokens, []healthapp.Probe{{Name: "redis", Check: tokens.Ping}}
+	case tokensMemory:
+		log.Warn("sessions are kept in memory and are lost on restart")
+		return memstorage.NewTokenStorage(), nil
+	default:
+		panic("unknown tokens storage: " + cfg.TokensStorage.Type)
+	}
+}
@@ -0,0 +1,239 @@
+package webhookapp
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/j0n1que/sso-service/internal/domain/models"
+	"github.com/j0n1que/sso-service/internal/storage"
+)
+
+const (
+	headerWebhookID = "X-Webhook-ID"
+	headerDelivery  = "X-Webhook-Delivery"
+	headerEvent     = "X-Webhook-Event"
+	headerTimestamp = "X-Webhook-Timestamp"
+	headerSignature = "X-Webhook-Signature"
+)
+
+type DeliveryStore interface {
+	Webhook(ctx context.Context, webhookID string) (models.Webhook, error)
+	ClaimDueDelivery(ctx context.Context, now time.Time, lease time.Duration) (models.WebhookDelivery, error)
+	RecordAttempt(ctx context.Context, deliveryID string, attempt models.DeliveryAttempt, status string, nextAttemptAt time.Time) error
+}
+
+type Options struct {
+	Timeout      time.Duration
+	MaxAttempts  int
+	BaseBackoff  time.Duration
+	MaxBackoff   time.Duration
+	PollInterval time.Duration
+}
+
+// App delivers queued webhook events. Failed deliveries are retried with
+// exponential backoff and end up in the dead letter list once they run out of
+// attempts.
+type App struct {
+	log    *slog.Logger
+	store  DeliveryStore
+	client *http.Client
+	opts   Options
+	stop   chan struct{}
+	done   chan struct{}
+}
+
+func New(log *slog.Logger, store DeliveryStore, opts Options) *App {
+	return &App{
+		log:    log,
+		store:  store,
+		client: &http.Client{Timeout: opts.Timeout},
+		opts:   opts,
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+}
+
+func (a *App) Run() {
+	const op = "webhookapp.Run"
+
+	defer close(a.done)
+
+	log := a.log.With(
+		slog.String("op", op),
+		slog.Duration("interval", a.opts.PollInterval),
+	)
+
+	log.Info("webhook delivery worker is running")
+
+	ticker := time.NewTicker(a.opts.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		a.deliverDue()
+
+		select {
+		case <-a.stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (a *App) Stop() {
+	const op = "webhookapp.Stop"
+
+	a.log.With(slog.String("op", op)).Info("stopping webhook delivery worker")
+
+	close(a.stop)
+	<-a.done
+}
+
+// deliverDue works through due deliveries until there are none left or the
+// worker is stopped.
+func (a *App) deliverDue() {
+	const op = "webhookapp.deliverDue"
+
+	log := a.log.With(slog.String("op", op))
+
+	for {
+		select {
+		case <-a.stop:
+			return
+		default:
+		}
+
+		// the lease outlives the request so that a slow endpoint doesn't get
+		// the same delivery from another worker
+		delivery, err := a.store.ClaimDueDelivery(context.Background(), time.Now().UTC(), 2*a.opts.Timeout)
+		if err != nil {
+			if !errors.Is(err, storage.ErrDeliveryNotFound) {
+				log.Error("failed to claim delivery", slog.String("error", err.Error()))
+			}
+			return
+		}
+
+		a.deliver(delivery)
+	}
+}
+
+func (a *App) deliver(delivery models.WebhookDelivery) {
+	const op = "webhookapp.deliver"
+
+	log := a.log.With(
+		slog.String("op", op),
+		slog.String("delivery_id", delivery.ID),
+		slog.String("webhook_id", delivery.WebhookID),
+	)
+
+	ctx := context.Background()
+
+	attempt := models.DeliveryAttempt{At: time.Now().UTC()}
+
+	webhook, err := a.store.Webhook(ctx, delivery.WebhookID)
+	switch {
+	case errors.Is(err, storage.ErrWebhookNotFound):
+		// nothing to retry, the endpoint was removed
+		attempt.Error = "webhook was deleted"
+		a.record(ctx, log, delivery, attempt, models.DeliveryStatusDead, attempt.At)
+		return
+	case err != nil:
+		log.Error("failed to get webhook", slog.String("error", err.Error()))
+		return
+	}
+
+	attempt.StatusCode, err = a.post(ctx, webhook, delivery)
+	attempt.Duration = time.Since(attempt.At)
+
+	if err == nil {
+		log.Info("webhook delivered", slog.Int("status_code", attempt.StatusCode))
+		a.record(ctx, log, delivery, attempt, models.DeliveryStatusDelivered, attempt.At)
+		return
+	}
+
+	attempt.Error = err.Error()
+
+	if delivery.Attempts+1 >= a.opts.MaxAttempts {
+		log.Warn("webhook delivery failed permanently", slog.String("error", err.Error()))
+		a.record(ctx, log, delivery, attempt, models.DeliveryStatusDead, attempt.At)
+		return
+	}
+
+	next := attempt.At.Add(a.backoff(delivery.Attempts))
+
+	log.Warn("webhook delivery failed",
+		slog.String("error", err.Error()),
+		slog.Time("next_attempt_at", next),
+	)
+	a.record(ctx, log, delivery, attempt, models.DeliveryStatusPending, next)
+}
+
+func (a *App) post(ctx context.Context, webhook models.Webhook, delivery models.WebhookDelivery) (int, error) {
+	body, err := json.Marshal(delivery.Event)
+	if err != nil {
+		return 0, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(headerWebhookID, webhook.ID)
+	req.Header.Set(headerDelivery, delivery.ID)
+	req.Header.Set(headerEvent, delivery.Event.Type)
+	req.Header.Set(headerTimestamp, timestamp)
+	req.Header.Set(headerSignature, "sha256="+Sign(webhook.Secret, timestamp, body))
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+
+	return resp.StatusCode, nil
+}
+
+func (a *App) record(ctx context.Context, log *slog.Logger, delivery models.WebhookDelivery, attempt models.DeliveryAttempt, status string, next time.Time) {
+	if err := a.store.RecordAttempt(ctx, delivery.ID, attempt, status, next); err != nil {
+		log.Error("failed to record delivery attempt", slog.String("error", err.Error()))
+	}
+}
+
+// backoff returns the delay before the next attempt after the given number of
+// failed attempts.
+func (a *App) backoff(failed int) time.Duration {
+	delay := a.opts.BaseBackoff
+	for i := 0; i < failed && delay < a.opts.MaxBackoff; i++ {
+		delay *= 2
+	}
+	return min(delay, a.opts.MaxBackoff)
+}
+
+// Sign returns the hex encoded HMAC-SHA256 of the timestamp and the body
+// joined with a dot. Receivers recompute it to verify the X-Webhook-Signature
+// header and reject stale timestamps to prevent replays.
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
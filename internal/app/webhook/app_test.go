@@ -0,0 +1,142 @@
+package webhookapp
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/j0n1que/sso-service/internal/domain/models"
+	"github.com/j0n1que/sso-service/internal/storage/memory"
+)
+
+// newApp returns a worker delivering to handler and its store holding one
+// pending delivery of the webhook with the given secret.
+func newApp(t *testing.T, secret string, maxAttempts int, handler http.HandlerFunc) (*App, *memory.WebhookDAO) {
+	t.Helper()
+
+	ctx := context.Background()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	store := memory.NewWebhookDAO()
+	if err := store.SaveWebhook(ctx, models.Webhook{ID: "wh", URL: server.URL, Secret: secret}); err != nil {
+		t.Fatal(err)
+	}
+
+	err := store.EnqueueDeliveries(ctx, models.WebhookDelivery{
+		ID:        "d1",
+		WebhookID: "wh",
+		Event:     models.Event{ID: "e1", Type: models.EventUserRegistered, UserID: 1},
+		Status:    models.DeliveryStatusPending,
+		CreatedAt: time.Now().UTC(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	app := New(slog.New(slog.NewTextHandler(io.Discard, nil)), store, Options{
+		Timeout:     time.Second,
+		MaxAttempts: maxAttempts,
+		BaseBackoff: time.Second,
+		MaxBackoff:  time.Minute,
+	})
+
+	return app, store
+}
+
+// deliverNext delivers the pending delivery regardless of its backoff.
+func deliverNext(t *testing.T, app *App, store *memory.WebhookDAO) {
+	t.Helper()
+
+	delivery, err := store.ClaimDueDelivery(context.Background(), time.Now().Add(time.Hour), time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	app.deliver(delivery)
+}
+
+func TestDeliverySignature(t *testing.T) {
+	var (
+		header    http.Header
+		body      []byte
+		readError error
+	)
+
+	app, store := newApp(t, "s3cret", 1, func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header
+		body, readError = io.ReadAll(r.Body)
+	})
+
+	deliverNext(t, app, store)
+
+	if readError != nil {
+		t.Fatal(readError)
+	}
+
+	mac := hmac.New(sha256.New, []byte("s3cret"))
+	mac.Write([]byte(header.Get(headerTimestamp) + "." + string(body)))
+	want := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+
+	if got := header.Get(headerSignature); got != want {
+		t.Errorf("signature = %q, want %q", got, want)
+	}
+	if header.Get(headerTimestamp) == "" {
+		t.Error("timestamp header is missing")
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	app := New(slog.New(slog.NewTextHandler(io.Discard, nil)), nil, Options{
+		BaseBackoff: time.Second,
+		MaxBackoff:  10 * time.Second,
+	})
+
+	tests := []struct {
+		failed int
+		want   time.Duration
+	}{
+		{failed: 0, want: time.Second},
+		{failed: 1, want: 2 * time.Second},
+		{failed: 3, want: 8 * time.Second},
+		{failed: 4, want: 10 * time.Second},
+		{failed: 100, want: 10 * time.Second},
+	}
+
+	for _, tt := range tests {
+		if got := app.backoff(tt.failed); got != tt.want {
+			t.Errorf("backoff(%d) = %v, want %v", tt.failed, got, tt.want)
+		}
+	}
+}
+
+func TestDeliveryDiesAfterMaxAttempts(t *testing.T) {
+	ctx := context.Background()
+
+	app, store := newApp(t, "s3cret", 3, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+
+	for attempt := 1; attempt <= 3; attempt++ {
+		deliverNext(t, app, store)
+
+		dead, err := store.DeadDeliveries(ctx, 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if wantDead := attempt == 3; (len(dead) == 1) != wantDead {
+			t.Fatalf("after attempt %d dead deliveries = %d, want dead %v", attempt, len(dead), wantDead)
+		}
+		if attempt == 3 && dead[0].Attempts != 3 {
+			t.Errorf("dead delivery attempts = %d, want 3", dead[0].Attempts)
+		}
+	}
+}
@@ -2,6 +2,8 @@ package config
 
 import (
 	"flag"
+	"fmt"
+	"log/slog"
 	"os"
 	"time"
 
@@ -9,21 +11,255 @@ import (
 )
 
 type Config struct {
-	Env           string              `yml:"env" env-default:"local"`
-	UsersStorage  string              `yml:"usersstorage" env-required:"true"`
-	TokensStorage TokensStorageConfig `yml:"tokensstorage" env-required:"true"`
-	TokenTTL      time.Duration       `yml:"tokenttl" env-required:"true"`
-	GRPC          GRPCConfig          `yml:"grpc" env-required:"true"`
+	Env            string              `yml:"env" env:"SSO_ENV" env-default:"local"`
+	Storage        string              `yml:"storage" env:"SSO_STORAGE" env-default:"mongo"`
+	UsersStorage   string              `yml:"usersstorage" env:"SSO_USERSSTORAGE" secret:"url"`
+	Mongo          MongoConfig         `yml:"mongo" env-prefix:"SSO_MONGO_"`
+	Postgres       PostgresConfig      `yml:"postgres" env-prefix:"SSO_POSTGRES_"`
+	MigrateOnStart bool                `yml:"migrateonstart" env:"SSO_MIGRATEONSTART" env-default:"true"`
+	TokensStorage  TokensStorageConfig `yml:"tokensstorage" env-prefix:"SSO_TOKENSSTORAGE_" env-required:"true"`
+	UserCache      UserCacheConfig     `yml:"usercache" env-prefix:"SSO_USERCACHE_"`
+	TokenTTL       time.Duration       `yml:"tokenttl" env:"SSO_TOKENTTL" env-required:"true"`
+	JWT            JWTConfig           `yml:"jwt" env-prefix:"SSO_JWT_"`
+	UserRetention  time.Duration       `yml:"userretention" env:"SSO_USERRETENTION" env-default:"720h"`
+	GRPC           GRPCConfig          `yml:"grpc" env-prefix:"SSO_GRPC_" env-required:"true"`
+	Audit          AuditConfig         `yml:"audit" env-prefix:"SSO_AUDIT_"`
+	Events         EventsConfig        `yml:"events" env-prefix:"SSO_EVENTS_"`
+	Webhooks       WebhooksConfig      `yml:"webhooks" env-prefix:"SSO_WEBHOOKS_"`
+	Health         HealthConfig        `yml:"health" env-prefix:"SSO_HEALTH_"`
+	Metrics        MetricsConfig       `yml:"metrics" env-prefix:"SSO_METRICS_"`
+	Tracing        TracingConfig       `yml:"tracing" env-prefix:"SSO_TRACING_"`
+	Gateway        GatewayConfig       `yml:"gateway" env-prefix:"SSO_GATEWAY_"`
+	Log            LogConfig           `yml:"log" env-prefix:"SSO_LOG_"`
+
+	path string
+}
+
+// Path returns the file the config was loaded from.
+func (c *Config) Path() string {
+	return c.path
+}
+
+// LogConfig sets the minimum level of the service logs, debug, info, warn or
+// error, and their format, text or json. Empty settings use the defaults of
+// the environment.
+type LogConfig struct {
+	Level  string `yml:"level" env:"LEVEL"`
+	Format string `yml:"format" env:"FORMAT"`
 }
 
+// ParseLevel parses a level name as slog does, case-insensitively.
+func ParseLevel(level string) (slog.Level, error) {
+	var l slog.Level
+	if err := l.UnmarshalText([]byte(level)); err != nil {
+		return 0, fmt.Errorf("invalid log level %q", level)
+	}
+
+	return l, nil
+}
+
+// Access levels of gRPC methods, from the least to the most restricted.
+const (
+	// AccessOpen methods are called without authentication.
+	AccessOpen = "open"
+	// AccessGuest methods are called by users without a session.
+	AccessGuest = "guest"
+	// AccessUser methods are called by any user with a session.
+	AccessUser = "user"
+	// AccessAdmin methods are called by admins with a session.
+	AccessAdmin = "admin"
+)
+
 type GRPCConfig struct {
-	Port    int           `yml:"port"`
-	Timeout time.Duration `yml:"timeout"`
+	Port    int           `yml:"port" env:"PORT"`
+	Timeout time.Duration `yml:"timeout" env:"TIMEOUT"`
+	// MethodTimeouts overrides Timeout for the given full method names.
+	MethodTimeouts map[string]time.Duration `yml:"methodtimeouts" env:"METHODTIMEOUTS"`
+	// MethodAccess overrides the built-in access of the given full method
+	// names with one of the Access* levels.
+	MethodAccess   map[string]string `yml:"methodaccess" env:"METHODACCESS"`
+	MaxRecvMsgSize int               `yml:"maxrecvmsgsize" env:"MAXRECVMSGSIZE" env-default:"4194304"`
+	MaxSendMsgSize int               `yml:"maxsendmsgsize" env:"MAXSENDMSGSIZE" env-default:"4194304"`
+	Keepalive      KeepaliveConfig   `yml:"keepalive" env-prefix:"KEEPALIVE_"`
+	Reflection     bool              `yml:"reflection" env:"REFLECTION"`
+	TLS            TLSConfig         `yml:"tls" env-prefix:"TLS_"`
+	RateLimit      RateLimitConfig   `yml:"ratelimit" env-prefix:"RATELIMIT_"`
+}
+
+// RateLimitConfig limits every client IP to Rate calls per second on average
+// and Burst calls at once. It covers the REST gateway too, whose calls run
+// with the IP of the HTTP client. A zero Rate disables the limit.
+type RateLimitConfig struct {
+	Rate  float64 `yml:"rate" env:"RATE"`
+	Burst int     `yml:"burst" env:"BURST"`
+}
+
+type KeepaliveConfig struct {
+	Time                time.Duration `yml:"time" env:"TIME" env-default:"2h"`
+	Timeout             time.Duration `yml:"timeout" env:"TIMEOUT" env-default:"20s"`
+	MaxConnectionIdle   time.Duration `yml:"maxconnectionidle" env:"MAXCONNECTIONIDLE" env-default:"0s"`
+	MaxConnectionAge    time.Duration `yml:"maxconnectionage" env:"MAXCONNECTIONAGE" env-default:"0s"`
+	MinTime             time.Duration `yml:"mintime" env:"MINTIME" env-default:"5m"`
+	PermitWithoutStream bool          `yml:"permitwithoutstream" env:"PERMITWITHOUTSTREAM"`
 }
 
+// TLSConfig enables TLS when CertFile is set. With ClientCAFile clients may
+// authenticate with a certificate, and those matching Principals are treated
+// as machine callers.
+type TLSConfig struct {
+	CertFile          string            `yml:"certfile" env:"CERTFILE"`
+	KeyFile           string            `yml:"keyfile" env:"KEYFILE"`
+	ClientCAFile      string            `yml:"clientcafile" env:"CLIENTCAFILE"`
+	RequireClientCert bool              `yml:"requireclientcert" env:"REQUIRECLIENTCERT"`
+	MinVersion        string            `yml:"minversion" env:"MINVERSION" env-default:"1.2"`
+	Principals        []PrincipalConfig `yml:"principals"`
+}
+
+func (c TLSConfig) Enabled() bool {
+	return c.CertFile != ""
+}
+
+// PrincipalConfig maps a client certificate identity (URI or DNS SAN, or
+// common name) to the full method names it may call; "*" allows every method.
+type PrincipalConfig struct {
+	Identity string   `yml:"identity"`
+	Methods  []string `yml:"methods"`
+}
+
+// MongoConfig tunes the mongo storage whose URI is UsersStorage. Empty read
+// and write concerns keep the server defaults.
+type MongoConfig struct {
+	Database               string        `yml:"database" env:"DATABASE" env-default:"core"`
+	CollectionPrefix       string        `yml:"collectionprefix" env:"COLLECTIONPREFIX"`
+	MinPoolSize            uint64        `yml:"minpoolsize" env:"MINPOOLSIZE" env-default:"0"`
+	MaxPoolSize            uint64        `yml:"maxpoolsize" env:"MAXPOOLSIZE" env-default:"100"`
+	MaxConnIdleTime        time.Duration `yml:"maxconnidletime" env:"MAXCONNIDLETIME" env-default:"0s"`
+	ConnectTimeout         time.Duration `yml:"connecttimeout" env:"CONNECTTIMEOUT" env-default:"30s"`
+	ServerSelectionTimeout time.Duration `yml:"serverselectiontimeout" env:"SERVERSELECTIONTIMEOUT" env-default:"30s"`
+	ReadConcern            string        `yml:"readconcern" env:"READCONCERN"`
+	ReadPreference         string        `yml:"readpreference" env:"READPREFERENCE" env-default:"primary"`
+	// WriteConcern is "majority", a number of nodes or a tag set name.
+	WriteConcern string        `yml:"writeconcern" env:"WRITECONCERN"`
+	Journal      bool          `yml:"journal" env:"JOURNAL"`
+	WriteTimeout time.Duration `yml:"writetimeout" env:"WRITETIMEOUT" env-default:"0s"`
+	RetryWrites  bool          `yml:"retrywrites" env:"RETRYWRITES" env-default:"true"`
+	RetryReads   bool          `yml:"retryreads" env:"RETRYREADS" env-default:"true"`
+	// AllowStandalone runs on a standalone server, which has no transactions,
+	// so a change and its event can be stored one without the other.
+	AllowStandalone bool `yml:"allowstandalone" env:"ALLOWSTANDALONE"`
+}
+
+type PostgresConfig struct {
+	DSN      string `yml:"dsn" env:"DSN" secret:"url"`
+	MaxConns int32  `yml:"maxconns" env:"MAXCONNS" env-default:"10"`
+}
+
+// TokensStorageConfig configures the token store. For redis, Addrs lists
+// sentinel or cluster nodes and takes precedence over Addr; MasterName selects
+// Sentinel failover and Cluster selects Redis Cluster.
 type TokensStorageConfig struct {
-	Addr     string `yml:"addr"`
-	Password string `yml:"password"`
+	Type             string         `yml:"type" env:"TYPE" env-default:"redis"`
+	Addr             string         `yml:"addr" env:"ADDR"`
+	Addrs            []string       `yml:"addrs" env:"ADDRS"`
+	MasterName       string         `yml:"mastername" env:"MASTERNAME"`
+	Cluster          bool           `yml:"cluster" env:"CLUSTER"`
+	Username         string         `yml:"username" env:"USERNAME"`
+	Password         string         `yml:"password" env:"PASSWORD" secret:"true"`
+	SentinelPassword string         `yml:"sentinelpassword" env:"SENTINELPASSWORD" secret:"true"`
+	DB               int            `yml:"db" env:"DB" env-default:"0"`
+	KeyPrefix        string         `yml:"keyprefix" env:"KEYPREFIX"`
+	TLS              RedisTLSConfig `yml:"tls" env-prefix:"TLS_"`
+	PoolSize         int            `yml:"poolsize" env:"POOLSIZE" env-default:"0"`
+	MinIdleConns     int            `yml:"minidleconns" env:"MINIDLECONNS" env-default:"0"`
+	MaxRetries       int            `yml:"maxretries" env:"MAXRETRIES" env-default:"3"`
+	DialTimeout      time.Duration  `yml:"dialtimeout" env:"DIALTIMEOUT" env-default:"5s"`
+	ReadTimeout      time.Duration  `yml:"readtimeout" env:"READTIMEOUT" env-default:"3s"`
+	WriteTimeout     time.Duration  `yml:"writetimeout" env:"WRITETIMEOUT" env-default:"3s"`
+	PoolTimeout      time.Duration  `yml:"pooltimeout" env:"POOLTIMEOUT" env-default:"4s"`
+	IdleTimeout      time.Duration  `yml:"idletimeout" env:"IDLETIMEOUT" env-default:"5m"`
+}
+
+// RedisTLSConfig enables TLS to redis. CertFile and KeyFile are only needed
+// when the server asks for a client certificate.
+type RedisTLSConfig struct {
+	Enabled            bool   `yml:"enabled" env:"ENABLED"`
+	CAFile             string `yml:"cafile" env:"CAFILE"`
+	CertFile           string `yml:"certfile" env:"CERTFILE"`
+	KeyFile            string `yml:"keyfile" env:"KEYFILE"`
+	ServerName         string `yml:"servername" env:"SERVERNAME"`
+	InsecureSkipVerify bool   `yml:"insecureskipverify" env:"INSECURESKIPVERIFY"`
+}
+
+// JWTConfig configures the tokens issued on login. Secret is the HS256
+// signing key; the legacy SECRET variable is used when it is not set.
+type JWTConfig struct {
+	Secret    string   `yml:"secret" env:"SECRET" secret:"true"`
+	Issuer    string   `yml:"issuer" env:"ISSUER" env-default:"sso"`
+	Audiences []string `yml:"audiences" env:"AUDIENCES" env-default:"sso"`
+}
+
+// UserCacheConfig configures the cache of users looked up by the auth
+// interceptor. Type is "memory", "redis" (the tokens storage redis) or "none".
+type UserCacheConfig struct {
+	Type string        `yml:"type" env:"TYPE" env-default:"memory"`
+	Size int           `yml:"size" env:"SIZE" env-default:"10000"`
+	TTL  time.Duration `yml:"ttl" env:"TTL" env-default:"30s"`
+}
+
+type AuditConfig struct {
+	HashChain bool `yml:"hashchain" env:"HASHCHAIN" env-default:"true"`
+}
+
+type EventsConfig struct {
+	Publisher    string        `yml:"publisher" env:"PUBLISHER" env-default:"redis"`
+	Stream       string        `yml:"stream" env:"STREAM" env-default:"sso:events"`
+	PollInterval time.Duration `yml:"pollinterval" env:"POLLINTERVAL" env-default:"1s"`
+}
+
+type WebhooksConfig struct {
+	Timeout      time.Duration `yml:"timeout" env:"TIMEOUT" env-default:"10s"`
+	MaxAttempts  int           `yml:"maxattempts" env:"MAXATTEMPTS" env-default:"8"`
+	BaseBackoff  time.Duration `yml:"basebackoff" env:"BASEBACKOFF" env-default:"10s"`
+	MaxBackoff   time.Duration `yml:"maxbackoff" env:"MAXBACKOFF" env-default:"1h"`
+	PollInterval time.Duration `yml:"pollinterval" env:"POLLINTERVAL" env-default:"1s"`
+}
+
+type HealthConfig struct {
+	Port     int           `yml:"port" env:"PORT" env-default:"8080"`
+	Interval time.Duration `yml:"interval" env:"INTERVAL" env-default:"10s"`
+	Timeout  time.Duration `yml:"timeout" env:"TIMEOUT" env-default:"2s"`
+}
+
+type MetricsConfig struct {
+	Port             int           `yml:"port" env:"PORT" env-default:"9090"`
+	SessionsInterval time.Duration `yml:"sessionsinterval" env:"SESSIONSINTERVAL" env-default:"30s"`
+}
+
+type TracingConfig struct {
+	Exporter    string  `yml:"exporter" env:"EXPORTER" env-default:"none"`
+	Endpoint    string  `yml:"endpoint" env:"ENDPOINT" env-default:"localhost:4317"`
+	Insecure    bool    `yml:"insecure" env:"INSECURE" env-default:"true"`
+	SampleRatio float64 `yml:"sampleratio" env:"SAMPLERATIO" env-default:"1"`
+	ServiceName string  `yml:"servicename" env:"SERVICENAME" env-default:"sso"`
+}
+
+// GatewayConfig serves the gateway over TLS, reloaded like the gRPC one.
+// Since the gateway carries passwords, it refuses to serve plain HTTP unless
+// Insecure is set, which is meant for local runs only. Principals do not
+// apply to the gateway.
+type GatewayConfig struct {
+	Port     int        `yml:"port" env:"PORT" env-default:"8082"`
+	CORS     CORSConfig `yml:"cors" env-prefix:"CORS_"`
+	TLS      TLSConfig  `yml:"tls" env-prefix:"TLS_"`
+	Insecure bool       `yml:"insecure" env:"INSECURE"`
+}
+
+// CORSConfig lists the browser origins allowed to call the gateway; "*"
+// allows any origin.
+type CORSConfig struct {
+	AllowedOrigins []string      `yml:"allowedorigins" env:"ALLOWEDORIGINS"`
+	AllowedHeaders []string      `yml:"allowedheaders" env:"ALLOWEDHEADERS" env-default:"Authorization,Content-Type,Telegram-Login,X-Request-Id"`
+	MaxAge         time.Duration `yml:"maxage" env:"MAXAGE" env-default:"10m"`
 }
 
 func MustLoad() *Config {
@@ -37,13 +273,38 @@ func MustLoad() *Config {
 		panic("config file does not exist: " + path)
 	}
 
+	cfg, err := Load(path)
+	if err != nil {
+		panic(err.Error())
+	}
+
+	return cfg
+}
+
+// Load reads the config file at path, applies the environment and validates
+// the result.
+func Load(path string) (*Config, error) {
+	if err := resolveFileEnv(); err != nil {
+		return nil, fmt.Errorf("failed to read secret files: %w", err)
+	}
+
 	var cfg Config
 
 	if err := cleanenv.ReadConfig(path, &cfg); err != nil {
-		panic("failed to read config: " + err.Error())
+		return nil, fmt.Errorf("failed to read config: %w", err)
+	}
+
+	if cfg.JWT.Secret == "" {
+		cfg.JWT.Secret = os.Getenv("SECRET")
 	}
 
-	return &cfg
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+
+	cfg.path = path
+
+	return &cfg, nil
 }
 
 func fetchConfigPath() string {
@@ -0,0 +1,210 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// MinJWTSecretLength is the shortest accepted HS256 key, the size of its hash
+// output as RFC 7518 requires.
+const MinJWTSecretLength = 32
+
+const redacted = "[REDACTED]"
+
+// fromFile holds the variables set by resolveFileEnv, which are read again
+// on every load so that rotated secrets are picked up.
+var fromFile = make(map[string]bool)
+
+// resolveFileEnv sets every SSO_* variable, and the legacy SECRET, from the
+// file named by the same variable with a _FILE suffix, as Docker and
+// Kubernetes secrets are mounted.
+func resolveFileEnv() error {
+	for _, kv := range os.Environ() {
+		name, path, _ := strings.Cut(kv, "=")
+
+		key, ok := strings.CutSuffix(name, "_FILE")
+		if !ok || (!strings.HasPrefix(key, "SSO_") && key != "SECRET") {
+			continue
+		}
+
+		if _, set := os.LookupEnv(key); set && !fromFile[key] {
+			return fmt.Errorf("both %s and %s are set", key, name)
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+
+		if err := os.Setenv(key, strings.TrimRight(string(data), "\r\n")); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		fromFile[key] = true
+	}
+
+	return nil
+}
+
+// Validate reports every missing or unusable setting at once, naming the
+// variables that provide it.
+func (c *Config) Validate() error {
+	var errs []error
+
+	switch {
+	case c.JWT.Secret == "":
+		errs = append(errs, errors.New("jwt secret is not set, set SSO_JWT_SECRET or SSO_JWT_SECRET_FILE"))
+	case len(c.JWT.Secret) < MinJWTSecretLength:
+		errs = append(errs, fmt.Errorf("jwt secret must be at least %d bytes, got %d", MinJWTSecretLength, len(c.JWT.Secret)))
+	}
+
+	switch c.Storage {
+	case "mongo":
+		if c.UsersStorage == "" {
+			errs = append(errs, errors.New("mongo uri is not set, set usersstorage, SSO_USERSSTORAGE or SSO_USERSSTORAGE_FILE"))
+		}
+	case "postgres":
+		if c.Postgres.DSN == "" {
+			errs = append(errs, errors.New("postgres dsn is not set, set postgres.dsn, SSO_POSTGRES_DSN or SSO_POSTGRES_DSN_FILE"))
+		}
+	}
+
+	if c.TokensStorage.Type == "redis" && c.TokensStorage.Addr == "" && len(c.TokensStorage.Addrs) == 0 {
+		errs = append(errs, errors.New("redis address is not set, set tokensstorage.addr or SSO_TOKENSSTORAGE_ADDR"))
+	}
+
+	if tls := c.TokensStorage.TLS; (tls.CertFile == "") != (tls.KeyFile == "") {
+		errs = append(errs, errors.New("redis tls needs both certfile and keyfile"))
+	}
+
+	if c.Log.Level != "" {
+		if _, err := ParseLevel(c.Log.Level); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	switch c.Log.Format {
+	case "", "text", "json":
+	default:
+		errs = append(errs, fmt.Errorf("invalid log format %q, want text or json", c.Log.Format))
+	}
+
+	if rl := c.GRPC.RateLimit; rl.Rate < 0 || (rl.Rate > 0 && rl.Burst < 1) {
+		errs = append(errs, errors.New("grpc rate limit needs a positive burst and a rate of zero or more"))
+	}
+
+	for method, level := range c.GRPC.MethodAccess {
+		switch level {
+		case AccessOpen, AccessGuest, AccessUser, AccessAdmin:
+		default:
+			errs = append(errs, fmt.Errorf("invalid access %q of %s, want open, guest, user or admin", level, method))
+		}
+		if !strings.HasPrefix(method, "/") {
+			errs = append(errs, fmt.Errorf("method access needs full method names, got %q", method))
+		}
+	}
+
+	if tls := c.GRPC.TLS; tls.Enabled() && tls.KeyFile == "" {
+		errs = append(errs, errors.New("grpc tls needs a keyfile"))
+	}
+
+	switch tls := c.Gateway.TLS; {
+	case tls.Enabled() && tls.KeyFile == "":
+		errs = append(errs, errors.New("gateway tls needs a keyfile"))
+	case !tls.Enabled() && !c.Gateway.Insecure:
+		errs = append(errs, errors.New("gateway tls is not set, set gateway.tls.certfile and keyfile, or gateway.insecure to serve plain HTTP"))
+	}
+
+	return errors.Join(errs...)
+}
+
+// String renders the config as JSON with the fields tagged secret hidden, so
+// that it can be logged. Secret URLs keep everything but their password.
+func (c Config) String() string {
+	data, err := json.Marshal(redact(reflect.ValueOf(c)))
+	if err != nil {
+		return "config: " + err.Error()
+	}
+
+	return string(data)
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+func redact(v reflect.Value) any {
+	if v.Type() == durationType {
+		return v.Interface().(time.Duration).String()
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+		fields := make(map[string]any, v.NumField())
+		for i := 0; i < v.NumField(); i++ {
+			field := v.Type().Field(i)
+			if !field.IsExported() {
+				continue
+			}
+
+			name := field.Tag.Get("yml")
+			if name == "" {
+				name = field.Name
+			}
+
+			switch field.Tag.Get("secret") {
+			case "true":
+				fields[name] = redactString(v.Field(i).String())
+			case "url":
+				fields[name] = redactURL(v.Field(i).String())
+			default:
+				fields[name] = redact(v.Field(i))
+			}
+		}
+		return fields
+	case reflect.Slice:
+		items := make([]any, v.Len())
+		for i := range items {
+			items[i] = redact(v.Index(i))
+		}
+		return items
+	case reflect.Map:
+		items := make(map[string]any, v.Len())
+		for it := v.MapRange(); it.Next(); {
+			items[fmt.Sprint(it.Key().Interface())] = redact(it.Value())
+		}
+		return items
+	default:
+		return v.Interface()
+	}
+}
+
+func redactString(s string) string {
+	if s == "" {
+		return ""
+	}
+	return redacted
+}
+
+// redactURL hides the password of a URL. Anything that does not parse as a
+// URL, such as a keyword/value DSN, is hidden entirely.
+func redactURL(s string) string {
+	if s == "" {
+		return ""
+	}
+
+	u, err := url.Parse(s)
+	if err != nil || u.Scheme == "" {
+		return redacted
+	}
+
+	if q := u.Query(); q.Has("password") {
+		q.Set("password", redacted)
+		u.RawQuery = q.Encode()
+	}
+
+	return u.Redacted()
+}
@@ -0,0 +1,278 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// reloadDelay groups the bursts of events editors and config map updates
+// produce into a single reload.
+const reloadDelay = 200 * time.Millisecond
+
+// reloadable are the settings applied without a restart. Changes to anything
+// else are logged on every reload and take effect on the next start.
+var reloadable = []string{
+	"tokenttl",
+	"userretention",
+	"grpc.timeout",
+	"grpc.methodtimeouts",
+	"grpc.methodaccess",
+	"grpc.ratelimit",
+	"grpc.tls.principals",
+	"log.level",
+}
+
+// Watcher reloads the config when its file changes or the process receives
+// SIGHUP. A reload that fails to load or validate is logged and the previous
+// config stays in effect.
+type Watcher struct {
+	log     *slog.Logger
+	path    string
+	current atomic.Pointer[Config]
+
+	mu          sync.Mutex
+	subscribers []func(cfg *Config)
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+func NewWatcher(log *slog.Logger, cfg *Config) *Watcher {
+	w := &Watcher{
+		log:  log,
+		path: cfg.Path(),
+		stop: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+	w.current.Store(cfg)
+
+	return w
+}
+
+// Current returns the config in effect: the one the process started with and
+// the reloadable settings of the latest valid config. It must not be
+// modified.
+func (w *Watcher) Current() *Config {
+	return w.current.Load()
+}
+
+// Subscribe registers fn to be called with every new config. Calls are made
+// from the watcher goroutine, one at a time.
+func (w *Watcher) Subscribe(fn func(cfg *Config)) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.subscribers = append(w.subscribers, fn)
+}
+
+// Run watches the directory of the config file, so that files replaced by a
+// rename, as Kubernetes does for config maps, are followed too.
+func (w *Watcher) Run() {
+	const op = "config.Watcher.Run"
+
+	log := w.log.With(slog.String("op", op), slog.String("path", w.path))
+
+	defer close(w.done)
+
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	var events chan fsnotify.Event
+	var errs chan error
+
+	fsw, err := fsnotify.NewWatcher()
+	if err == nil {
+		err = fsw.Add(filepath.Dir(w.path))
+	}
+	if err != nil {
+		log.Error("failed to watch config file, only SIGHUP reloads it", slog.String("error", err.Error()))
+	} else {
+		defer fsw.Close()
+		events, errs = fsw.Events, fsw.Errors
+	}
+
+	timer := time.NewTimer(reloadDelay)
+	timer.Stop()
+
+	for {
+		select {
+		case <-w.stop:
+			return
+		case <-hup:
+			log.Info("reloading config on SIGHUP")
+			w.reload()
+		case event := <-events:
+			if event.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Rename|fsnotify.Remove) != 0 {
+				timer.Reset(reloadDelay)
+			}
+		case err := <-errs:
+			log.Error("config watch failed", slog.String("error", err.Error()))
+		case <-timer.C:
+			w.reload()
+		}
+	}
+}
+
+func (w *Watcher) Stop() {
+	close(w.stop)
+	<-w.done
+}
+
+func (w *Watcher) reload() {
+	const op = "config.Watcher.reload"
+
+	log := w.log.With(slog.String("op", op), slog.String("path", w.path))
+
+	next, err := Load(w.path)
+	if err != nil {
+		log.Error("config reload rejected, keeping the previous config", slog.String("error", err.Error()))
+		return
+	}
+
+	current := w.current.Load()
+
+	changes := diff(current, next)
+	if len(changes) == 0 {
+		log.Debug("config unchanged")
+		return
+	}
+
+	applied := false
+	for _, c := range changes {
+		attrs := []any{slog.String("key", c.key), slog.String("old", c.old), slog.String("new", c.value)}
+		if !isReloadable(c.key) {
+			log.Warn("config changed, restart to apply", attrs...)
+			continue
+		}
+		log.Info("config changed", attrs...)
+		applied = true
+	}
+
+	if !applied {
+		return
+	}
+
+	next = withReloadable(current, next)
+	w.current.Store(next)
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for _, fn := range w.subscribers {
+		fn(next)
+	}
+}
+
+func isReloadable(key string) bool {
+	for _, prefix := range reloadable {
+		if key == prefix || strings.HasPrefix(key, prefix+".") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// withReloadable returns a copy of current with the reloadable settings of
+// next, so that the rest keeps the values the process runs with.
+func withReloadable(current, next *Config) *Config {
+	cfg := *current
+
+	for _, key := range reloadable {
+		copySetting(reflect.ValueOf(&cfg).Elem(), reflect.ValueOf(next).Elem(), strings.Split(key, "."))
+	}
+
+	return &cfg
+}
+
+// copySetting copies the setting at the dotted path of yml names from src to
+// dst. It panics on paths that name no setting, which is a bug in reloadable.
+func copySetting(dst, src reflect.Value, path []string) {
+	if len(path) == 0 {
+		dst.Set(src)
+		return
+	}
+
+	for i := 0; i < dst.NumField(); i++ {
+		if dst.Type().Field(i).Tag.Get("yml") == path[0] {
+			copySetting(dst.Field(i), src.Field(i), path[1:])
+			return
+		}
+	}
+
+	panic("config: no setting " + path[0] + " to reload")
+}
+
+type change struct {
+	key        string
+	old, value string
+}
+
+// diff compares the redacted forms of the configs, so secrets never show up
+// in the result and a changed secret is not reported.
+func diff(prev, next *Config) []change {
+	before, after := flatten(prev), flatten(next)
+
+	var changes []change
+	for key, value := range after {
+		if before[key] != value {
+			changes = append(changes, change{key: key, old: before[key], value: value})
+		}
+	}
+	for key, value := range before {
+		if _, ok := after[key]; !ok {
+			changes = append(changes, change{key: key, old: value})
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].key < changes[j].key
+	})
+
+	return changes
+}
+
+// flatten maps the dotted path of every setting of cfg to its value, in JSON
+// unless it is a string. Lists are kept whole.
+func flatten(cfg *Config) map[string]string {
+	flat := make(map[string]string)
+
+	var walk func(prefix string, v any)
+	walk = func(prefix string, v any) {
+		if fields, ok := v.(map[string]any); ok {
+			for name, field := range fields {
+				walk(strings.TrimPrefix(prefix+"."+name, "."), field)
+			}
+			return
+		}
+
+		if str, ok := v.(string); ok {
+			flat[prefix] = str
+			return
+		}
+
+		data, err := json.Marshal(v)
+		if err != nil {
+			data = []byte(fmt.Sprint(v))
+		}
+		flat[prefix] = string(data)
+	}
+
+	walk("", redact(reflect.ValueOf(*cfg)))
+
+	return flat
+}
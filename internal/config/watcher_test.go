@@ -0,0 +1,78 @@
+package config
+
+import (
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeConfig(t *testing.T, path string, replacer *strings.Replacer) {
+	t.Helper()
+
+	data, err := os.ReadFile("../../config/dev.yml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(path, []byte(replacer.Replace(string(data))), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReloadKeepsSettingsThatNeedRestart(t *testing.T) {
+	t.Setenv("SSO_JWT_SECRET", "0123456789abcdef0123456789abcdef")
+
+	path := filepath.Join(t.TempDir(), "config.yml")
+	writeConfig(t, path, strings.NewReplacer())
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := NewWatcher(slog.New(slog.NewTextHandler(io.Discard, nil)), cfg)
+
+	var notified []*Config
+	w.Subscribe(func(cfg *Config) {
+		notified = append(notified, cfg)
+	})
+
+	writeConfig(t, path, strings.NewReplacer("tokenttl: 720h", "tokenttl: 1h", "port: 44044", "port: 44045"))
+	w.reload()
+
+	current := w.Current()
+	if current.TokenTTL != time.Hour {
+		t.Errorf("TokenTTL = %v, want %v", current.TokenTTL, time.Hour)
+	}
+	if current.GRPC.Port != cfg.GRPC.Port {
+		t.Errorf("GRPC.Port = %d, want %d until a restart", current.GRPC.Port, cfg.GRPC.Port)
+	}
+	if len(notified) != 1 || notified[0] != current {
+		t.Fatalf("subscribers notified %d times, want once with the current config", len(notified))
+	}
+
+	// the port change alone still waits for a restart
+	writeConfig(t, path, strings.NewReplacer("tokenttl: 720h", "tokenttl: 1h", "port: 44044", "port: 44046"))
+	w.reload()
+
+	if w.Current() != current {
+		t.Error("Current() changed by a reload without reloadable changes")
+	}
+	if len(notified) != 1 {
+		t.Errorf("subscribers notified %d times, want once", len(notified))
+	}
+}
+
+func TestReloadableSettingsExist(t *testing.T) {
+	defer func() {
+		if r := recover(); r != nil {
+			t.Fatal(r)
+		}
+	}()
+
+	withReloadable(&Config{}, &Config{})
+}
@@ -0,0 +1,113 @@
+package models
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	AuditActionRegister       = "register"
+	AuditActionLogin          = "login"
+	AuditActionChangePassword = "change_password"
+	AuditActionMakeAdmin      = "make_admin"
+	AuditActionRevokeAdmin    = "revoke_admin"
+	AuditActionDisableUser    = "disable_user"
+	AuditActionEnableUser     = "enable_user"
+	AuditActionDeleteUser     = "delete_user"
+	AuditActionDeleteToken    = "delete_token"
+	AuditActionRevokeToken    = "revoke_token"
+	AuditActionExportUserData = "export_user_data"
+	AuditActionEraseUserData  = "erase_user_data"
+
+	AuditOutcomeSuccess = "success"
+	AuditOutcomeFailure = "failure"
+)
+
+// AuditEntry is a single security relevant event. Users are referenced only by
+// their numeric IDs so entries stay meaningful after the user is erased.
+type AuditEntry struct {
+	Seq       int64     `bson:"_id" json:"seq"`
+	Timestamp time.Time `bson:"timestamp" json:"timestamp"`
+	ActorID   int64     `bson:"actorId" json:"actor_id"`
+	TargetID  int64     `bson:"targetId" json:"target_id"`
+	Action    string    `bson:"action" json:"action"`
+	Outcome   string    `bson:"outcome" json:"outcome"`
+	Reason    string    `bson:"reason,omitempty" json:"reason,omitempty"`
+	IP        string    `bson:"ip" json:"ip"`
+	UserAgent string    `bson:"userAgent" json:"user_agent"`
+	PrevHash  string    `bson:"prevHash,omitempty" json:"prev_hash,omitempty"`
+	Hash      string    `bson:"hash,omitempty" json:"hash,omitempty"`
+}
+
+// ChainHash is the hash of the entry linked to the hash of its predecessor in
+// PrevHash. Every storage backend computes it the same way.
+func (e AuditEntry) ChainHash() string {
+	fields := []string{
+		strconv.FormatInt(e.Seq, 10),
+		e.Timestamp.UTC().Format(time.RFC3339Nano),
+		strconv.FormatInt(e.ActorID, 10),
+		strconv.FormatInt(e.TargetID, 10),
+		e.Action,
+		e.Outcome,
+		e.Reason,
+		e.IP,
+		e.UserAgent,
+		e.PrevHash,
+	}
+
+	sum := sha256.Sum256([]byte(strings.Join(fields, "\x1f")))
+
+	return hex.EncodeToString(sum[:])
+}
+
+// AuditChain checks audit entries fed to it in sequence order. Entries
+// written while chaining was off carry no hash and are only checked for
+// sequence continuity.
+type AuditChain struct {
+	// Chaining makes a blank hash a break once the first hashed entry has
+	// been seen, as the log is then written with hashes.
+	Chaining bool
+
+	prev    AuditEntry
+	chained bool
+}
+
+// Check returns the sequence number of the first broken entry, entry's or
+// a missing one before it, or zero if entry continues the log.
+func (c *AuditChain) Check(entry AuditEntry) int64 {
+	if entry.Seq != c.prev.Seq+1 {
+		return c.prev.Seq + 1
+	}
+
+	switch {
+	case entry.Hash == "" && c.Chaining && c.chained:
+		return entry.Seq
+	case entry.Hash != "" && (entry.PrevHash != c.prev.Hash || entry.Hash != entry.ChainHash()):
+		return entry.Seq
+	}
+
+	if entry.Hash != "" {
+		c.chained = true
+	}
+	c.prev = entry
+
+	return 0
+}
+
+// AuditFilter narrows down audit log queries. Zero values match everything.
+// UserID matches entries where the user is either the actor or the target.
+type AuditFilter struct {
+	ActorID  int64
+	TargetID int64
+	UserID   int64
+	Action   string
+	Outcome  string
+	Since    time.Time
+	Until    time.Time
+	// AfterSeq continues a previous query, entries are returned newest first.
+	AfterSeq int64
+	Limit    int64
+}
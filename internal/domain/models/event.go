@@ -0,0 +1,50 @@
+package models
+
+import "time"
+
+const (
+	EventUserRegistered      = "user.registered"
+	EventUserPromoted        = "user.promoted"
+	EventUserDemoted         = "user.demoted"
+	EventUserPasswordChanged = "user.password_changed"
+	EventUserLoggedIn        = "user.logged_in"
+	EventUserLoggedOut       = "user.logged_out"
+	EventUserDisabled        = "user.disabled"
+	EventUserEnabled         = "user.enabled"
+	EventUserDeleted         = "user.deleted"
+	EventUserErased          = "user.erased"
+)
+
+// EventTypes lists every event type the service publishes.
+var EventTypes = []string{
+	EventUserRegistered,
+	EventUserPromoted,
+	EventUserDemoted,
+	EventUserPasswordChanged,
+	EventUserLoggedIn,
+	EventUserLoggedOut,
+	EventUserDisabled,
+	EventUserEnabled,
+	EventUserDeleted,
+	EventUserErased,
+}
+
+// Event is a domain event about a user lifecycle change. Consumers get events
+// at least once and should deduplicate them by ID.
+type Event struct {
+	ID         string    `bson:"_id" json:"id"`
+	Type       string    `bson:"type" json:"type"`
+	UserID     int64     `bson:"userId" json:"user_id"`
+	ActorID    int64     `bson:"actorId" json:"actor_id"`
+	OccurredAt time.Time `bson:"occurredAt" json:"occurred_at"`
+	// Login and TelegramLogin are set for user.registered only.
+	Login         string `bson:"login,omitempty" json:"login,omitempty"`
+	TelegramLogin string `bson:"telegramLogin,omitempty" json:"telegram_login,omitempty"`
+}
+
+// ErasePersonalData clears the fields identifying the user beyond its ID, as
+// done to stored events once the user is erased.
+func (e *Event) ErasePersonalData() {
+	e.Login = ""
+	e.TelegramLogin = ""
+}
@@ -0,0 +1,32 @@
+package models
+
+import "time"
+
+// UserDataExport is the machine-readable bundle of everything the service
+// stores about a single user.
+type UserDataExport struct {
+	ExportedAt   time.Time       `json:"exported_at"`
+	Profile      ProfileExport   `json:"profile"`
+	Sessions     []SessionExport `json:"sessions"`
+	AuditEntries []AuditEntry    `json:"audit_entries"`
+}
+
+// ProfileExport holds the stored profile of a user. The password hash is
+// intentionally left out, only the fact that it is stored is reported.
+type ProfileExport struct {
+	ID            int64      `json:"id"`
+	Login         string     `json:"login"`
+	TelegramLogin string     `json:"telegram_login"`
+	IsAdmin       bool       `json:"is_admin"`
+	Status        string     `json:"status"`
+	DeletedAt     *time.Time `json:"deleted_at,omitempty"`
+	UpdatedAt     *time.Time `json:"updated_at,omitempty"`
+	HasPassword   bool       `json:"has_password"`
+}
+
+// SessionExport identifies a session by the ID of its token. The token itself
+// is left out since it is a live credential.
+type SessionExport struct {
+	TokenID   string    `json:"token_id,omitempty"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
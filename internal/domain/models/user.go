@@ -1,9 +1,50 @@
 package models
 
+import "time"
+
+const (
+	UserStatusActive   = "active"
+	UserStatusDisabled = "disabled"
+	UserStatusDeleted  = "deleted"
+)
+
 type User struct {
-	ID            int64  `bson:"_id"`
-	Login         string `bson:"login"`
-	PassHash      []byte `bson:"passHash"`
-	IsAdmin       bool   `bson:"isAdmin"`
-	TelegramLogin string `bson:"telegramLogin"`
+	ID            int64     `bson:"_id"`
+	Login         string    `bson:"login"`
+	PassHash      []byte    `bson:"passHash"`
+	IsAdmin       bool      `bson:"isAdmin"`
+	TelegramLogin string    `bson:"telegramLogin"`
+	Status        string    `bson:"status"`
+	DeletedAt     time.Time `bson:"deletedAt,omitempty"`
+	// Version is incremented by every update. Users stored before versioning
+	// have version zero.
+	Version   int64     `bson:"version"`
+	UpdatedAt time.Time `bson:"updatedAt,omitempty"`
+}
+
+// Active reports whether the user may log in and use the service. Users stored
+// before statuses were introduced have an empty status and count as active.
+func (u User) Active() bool {
+	return u.Status == "" || u.Status == UserStatusActive
+}
+
+// UserAccess is what the auth check needs to know about a user. It is kept
+// apart from User so that caches never hold credentials.
+type UserAccess struct {
+	ID      int64  `json:"id"`
+	Status  string `json:"status"`
+	IsAdmin bool   `json:"is_admin"`
+}
+
+func (u User) Access() UserAccess {
+	return UserAccess{
+		ID:      u.ID,
+		Status:  u.Status,
+		IsAdmin: u.IsAdmin,
+	}
+}
+
+// Active reports whether the user may use the service, as User.Active does.
+func (u UserAccess) Active() bool {
+	return u.Status == "" || u.Status == UserStatusActive
 }
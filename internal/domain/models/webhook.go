@@ -0,0 +1,52 @@
+package models
+
+import "time"
+
+const (
+	DeliveryStatusPending   = "pending"
+	DeliveryStatusDelivered = "delivered"
+	DeliveryStatusDead      = "dead"
+)
+
+// Webhook is an HTTP endpoint that receives domain events. An empty EventTypes
+// list subscribes the endpoint to every event.
+type Webhook struct {
+	ID         string    `bson:"_id"`
+	URL        string    `bson:"url"`
+	Secret     string    `bson:"secret"`
+	EventTypes []string  `bson:"eventTypes"`
+	CreatedBy  int64     `bson:"createdBy"`
+	CreatedAt  time.Time `bson:"createdAt"`
+}
+
+// Subscribed reports whether the webhook wants events of the given type.
+func (w Webhook) Subscribed(eventType string) bool {
+	if len(w.EventTypes) == 0 {
+		return true
+	}
+	for _, t := range w.EventTypes {
+		if t == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+type WebhookDelivery struct {
+	ID            string            `bson:"_id"`
+	WebhookID     string            `bson:"webhookId"`
+	Event         Event             `bson:"event"`
+	Status        string            `bson:"status"`
+	Attempts      int               `bson:"attempts"`
+	NextAttemptAt time.Time         `bson:"nextAttemptAt"`
+	LastError     string            `bson:"lastError,omitempty"`
+	History       []DeliveryAttempt `bson:"history"`
+	CreatedAt     time.Time         `bson:"createdAt"`
+}
+
+type DeliveryAttempt struct {
+	At         time.Time     `bson:"at"`
+	StatusCode int           `bson:"statusCode,omitempty"`
+	Error      string        `bson:"error,omitempty"`
+	Duration   time.Duration `bson:"duration"`
+}
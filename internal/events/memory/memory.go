@@ -0,0 +1,46 @@
+package memory
+
+import (
+	"context"
+	"sync"
+
+	"github.com/j0n1que/sso-service/internal/domain/models"
+)
+
+// Publisher keeps published events in memory. It is meant for tests and local
+// runs where nothing consumes the events.
+type Publisher struct {
+	mu     sync.Mutex
+	events []models.Event
+}
+
+func New() *Publisher {
+	return &Publisher{}
+}
+
+func (p *Publisher) Publish(ctx context.Context, event models.Event) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if event.Type == models.EventUserErased {
+		for i := range p.events {
+			if p.events[i].UserID == event.UserID {
+				p.events[i].ErasePersonalData()
+			}
+		}
+	}
+
+	p.events = append(p.events, event)
+
+	return nil
+}
+
+// Events returns a copy of all published events in publishing order.
+func (p *Publisher) Events() []models.Event {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return append([]models.Event(nil), p.events...)
+}
+
+func (p *Publisher) Close() {}
@@ -0,0 +1,135 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/j0n1que/sso-service/internal/domain/models"
+)
+
+// maxStreamLen caps the stream so that it doesn't grow without bound when
+// nobody consumes it. Trimming is approximate.
+const maxStreamLen = 100000
+
+// eraseBatch is how many entries are read at once while erasing a user.
+const eraseBatch = 1000
+
+// Publisher appends domain events to a Redis stream.
+type Publisher struct {
+	db     redis.UniversalClient
+	stream string
+}
+
+func New(client redis.UniversalClient, stream string) *Publisher {
+	return &Publisher{
+		db:     client,
+		stream: stream,
+	}
+}
+
+func (p *Publisher) Close() {
+	p.db.Close()
+}
+
+func (p *Publisher) Publish(ctx context.Context, event models.Event) error {
+	const op = "events.redis.Publish"
+
+	if event.Type == models.EventUserErased {
+		if err := p.erase(ctx, event.UserID); err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	err = p.db.XAdd(ctx, &redis.XAddArgs{
+		Stream: p.stream,
+		MaxLen: maxStreamLen,
+		Approx: true,
+		Values: map[string]interface{}{
+			"id":      event.ID,
+			"type":    event.Type,
+			"user_id": event.UserID,
+			"payload": payload,
+		},
+	}).Err()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// erase deletes the entries of the user carrying personal data from the
+// stream, since entries cannot be changed in place. Consumers that read them
+// before are expected to handle user.erased themselves. The whole stream is
+// scanned, which is bounded by maxStreamLen.
+func (p *Publisher) erase(ctx context.Context, userID int64) error {
+	user := strconv.FormatInt(userID, 10)
+	start := "-"
+
+	for {
+		entries, err := p.db.XRangeN(ctx, p.stream, start, "+", eraseBatch).Result()
+		if err != nil {
+			return err
+		}
+
+		var ids []string
+		for _, entry := range entries {
+			if hasPersonalData(entry, user) {
+				ids = append(ids, entry.ID)
+			}
+		}
+
+		if len(ids) > 0 {
+			if err := p.db.XDel(ctx, p.stream, ids...).Err(); err != nil {
+				return err
+			}
+		}
+
+		if len(entries) < eraseBatch {
+			return nil
+		}
+
+		start, err = nextID(entries[len(entries)-1].ID)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func hasPersonalData(entry redis.XMessage, userID string) bool {
+	if fmt.Sprint(entry.Values["user_id"]) != userID {
+		return false
+	}
+
+	payload, _ := entry.Values["payload"].(string)
+
+	var event models.Event
+	if err := json.Unmarshal([]byte(payload), &event); err != nil {
+		// an entry that cannot be checked is dropped rather than kept
+		return true
+	}
+
+	return event.Login != "" || event.TelegramLogin != ""
+}
+
+// nextID returns the stream ID right after id, for servers without exclusive
+// ranges.
+func nextID(id string) (string, error) {
+	ms, seq, _ := strings.Cut(id, "-")
+
+	n, err := strconv.ParseUint(seq, 10, 64)
+	if err != nil {
+		return "", fmt.Errorf("invalid stream id %q", id)
+	}
+
+	return ms + "-" + strconv.FormatUint(n+1, 10), nil
+}
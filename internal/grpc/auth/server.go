@@ -2,12 +2,17 @@ package auth
 
 import (
 	"context"
+	"errors"
+	"strconv"
 
 	ssov1 "github.com/j0n1que/sso-protos/gen/go"
+	"github.com/j0n1que/sso-service/internal/domain/models"
+	authservice "github.com/j0n1que/sso-service/internal/services/auth"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
 type Auth interface {
@@ -20,15 +25,28 @@ type Auth interface {
 	MakeAdmin(ctx context.Context, userID int64) error
 	GetJWT(ctx context.Context, userID int64) (string, error)
 	DeleteJWT(ctx context.Context, userID int64) error
+	RevokeToken(ctx context.Context, token string) error
+	RevokeAdmin(ctx context.Context, userID int64) error
+	DisableUser(ctx context.Context, userID int64) error
+	EnableUser(ctx context.Context, userID int64) error
+	DeleteUser(ctx context.Context, userID int64) error
+	ExportUserData(ctx context.Context, userID int64) ([]byte, error)
+	EraseUserData(ctx context.Context, userID int64, dryRun bool) ([]string, error)
+	QueryAuditLog(ctx context.Context, filter models.AuditFilter) ([]models.AuditEntry, int64, error)
+	VerifyAuditLog(ctx context.Context) (int64, int64, error)
 }
 
 type ServerAPI struct {
 	ssov1.UnimplementedAuthServer
-	auth Auth
+	auth     Auth
+	webhooks Webhooks
 }
 
-func Register(gRPC *grpc.Server, auth Auth) {
-	ssov1.RegisterAuthServer(gRPC, &ServerAPI{auth: auth})
+func Register(gRPC *grpc.Server, auth Auth, webhooks Webhooks) *ServerAPI {
+	api := &ServerAPI{auth: auth, webhooks: webhooks}
+	ssov1.RegisterAuthServer(gRPC, api)
+
+	return api
 }
 
 func (s *ServerAPI) RegisterNewUser(ctx context.Context, req *ssov1.RegisterRequest) (*emptypb.Empty, error) {
@@ -69,6 +87,9 @@ func (s *ServerAPI) ChangePassword(ctx context.Context, req *ssov1.ChangePasswor
 		return nil, err
 	}
 	if err := s.auth.ChangePassword(ctx, req.GetUserId(), req.GetNewPassword()); err != nil {
+		if errors.Is(err, authservice.ErrUserNotFound) {
+			return nil, status.Error(codes.NotFound, "user not found")
+		}
 		return nil, status.Error(codes.Internal, "internal error")
 	}
 	return &emptypb.Empty{}, nil
@@ -100,6 +121,9 @@ func (s *ServerAPI) GetUserByTelegram(ctx context.Context, req *ssov1.GetUserByT
 
 func (s *ServerAPI) MakeAdmin(ctx context.Context, req *ssov1.MakeAdminRequest) (*emptypb.Empty, error) {
 	if err := s.auth.MakeAdmin(ctx, req.GetUserId()); err != nil {
+		if errors.Is(err, authservice.ErrUserNotFound) {
+			return nil, status.Error(codes.NotFound, "user not found")
+		}
 		return nil, status.Error(codes.Internal, "internal error")
 	}
 	return &emptypb.Empty{}, nil
@@ -122,6 +146,150 @@ func (s *ServerAPI) DeleteJWT(ctx context.Context, req *ssov1.DeleteJWTRequest)
 	return &emptypb.Empty{}, nil
 }
 
+func (s *ServerAPI) RevokeToken(ctx context.Context, req *ssov1.RevokeTokenRequest) (*emptypb.Empty, error) {
+	if req.GetToken() == "" {
+		return nil, status.Error(codes.InvalidArgument, "token is required")
+	}
+	if err := s.auth.RevokeToken(ctx, req.GetToken()); err != nil {
+		if errors.Is(err, authservice.ErrInvalidToken) {
+			return nil, status.Error(codes.InvalidArgument, "invalid token")
+		}
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+	return &emptypb.Empty{}, nil
+}
+
+func (s *ServerAPI) RevokeAdmin(ctx context.Context, req *ssov1.RevokeAdminRequest) (*emptypb.Empty, error) {
+	if err := s.auth.RevokeAdmin(ctx, req.GetUserId()); err != nil {
+		if errors.Is(err, authservice.ErrUserNotFound) {
+			return nil, status.Error(codes.NotFound, "user not found")
+		}
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+	return &emptypb.Empty{}, nil
+}
+
+func (s *ServerAPI) DisableUser(ctx context.Context, req *ssov1.DisableUserRequest) (*emptypb.Empty, error) {
+	if err := s.auth.DisableUser(ctx, req.GetUserId()); err != nil {
+		if errors.Is(err, authservice.ErrUserNotFound) {
+			return nil, status.Error(codes.NotFound, "user not found")
+		}
+		if errors.Is(err, authservice.ErrConflict) {
+			return nil, status.Error(codes.Aborted, "user was modified concurrently, retry")
+		}
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+	return &emptypb.Empty{}, nil
+}
+
+func (s *ServerAPI) EnableUser(ctx context.Context, req *ssov1.EnableUserRequest) (*emptypb.Empty, error) {
+	if err := s.auth.EnableUser(ctx, req.GetUserId()); err != nil {
+		if errors.Is(err, authservice.ErrUserNotFound) {
+			return nil, status.Error(codes.NotFound, "user not found")
+		}
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+	return &emptypb.Empty{}, nil
+}
+
+func (s *ServerAPI) DeleteUser(ctx context.Context, req *ssov1.DeleteUserRequest) (*emptypb.Empty, error) {
+	if err := s.auth.DeleteUser(ctx, req.GetUserId()); err != nil {
+		if errors.Is(err, authservice.ErrUserNotFound) {
+			return nil, status.Error(codes.NotFound, "user not found")
+		}
+		if errors.Is(err, authservice.ErrConflict) {
+			return nil, status.Error(codes.Aborted, "user was modified concurrently, retry")
+		}
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+	return &emptypb.Empty{}, nil
+}
+
+func (s *ServerAPI) ExportUserData(ctx context.Context, req *ssov1.ExportUserDataRequest) (*ssov1.ExportUserDataResponse, error) {
+	data, err := s.auth.ExportUserData(ctx, req.GetUserId())
+	if err != nil {
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+	return &ssov1.ExportUserDataResponse{
+		Data: data,
+	}, nil
+}
+
+func (s *ServerAPI) EraseUserData(ctx context.Context, req *ssov1.EraseUserDataRequest) (*ssov1.EraseUserDataResponse, error) {
+	erased, err := s.auth.EraseUserData(ctx, req.GetUserId(), req.GetDryRun())
+	if err != nil {
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+	return &ssov1.EraseUserDataResponse{
+		Erased: erased,
+		DryRun: req.GetDryRun(),
+	}, nil
+}
+
+func (s *ServerAPI) QueryAuditLog(ctx context.Context, req *ssov1.QueryAuditLogRequest) (*ssov1.QueryAuditLogResponse, error) {
+	if err := validateQueryAuditLog(req); err != nil {
+		return nil, err
+	}
+
+	filter := models.AuditFilter{
+		ActorID:  req.GetActorId(),
+		TargetID: req.GetTargetId(),
+		Action:   req.GetAction(),
+		Outcome:  req.GetOutcome(),
+		Limit:    req.GetPageSize(),
+	}
+	if req.GetSince() != nil {
+		filter.Since = req.GetSince().AsTime()
+	}
+	if req.GetUntil() != nil {
+		filter.Until = req.GetUntil().AsTime()
+	}
+	if req.GetPageToken() != "" {
+		filter.AfterSeq, _ = strconv.ParseInt(req.GetPageToken(), 10, 64)
+	}
+
+	entries, next, err := s.auth.QueryAuditLog(ctx, filter)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	resp := &ssov1.QueryAuditLogResponse{
+		Entries: make([]*ssov1.AuditEntry, len(entries)),
+	}
+	for i, entry := range entries {
+		resp.Entries[i] = &ssov1.AuditEntry{
+			Seq:       entry.Seq,
+			Timestamp: timestamppb.New(entry.Timestamp),
+			ActorId:   entry.ActorID,
+			TargetId:  entry.TargetID,
+			Action:    entry.Action,
+			Outcome:   entry.Outcome,
+			Reason:    entry.Reason,
+			Ip:        entry.IP,
+			UserAgent: entry.UserAgent,
+			PrevHash:  entry.PrevHash,
+			Hash:      entry.Hash,
+		}
+	}
+	if next != 0 {
+		resp.NextPageToken = strconv.FormatInt(next, 10)
+	}
+
+	return resp, nil
+}
+
+func (s *ServerAPI) VerifyAuditLog(ctx context.Context, req *emptypb.Empty) (*ssov1.VerifyAuditLogResponse, error) {
+	checked, brokenAt, err := s.auth.VerifyAuditLog(ctx)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+	return &ssov1.VerifyAuditLogResponse{
+		Valid:       brokenAt == 0,
+		Checked:     checked,
+		BrokenAtSeq: brokenAt,
+	}, nil
+}
+
 func validateRegister(req *ssov1.RegisterRequest) error {
 	if req.GetLogin() == "" {
 		return status.Error(codes.InvalidArgument, "login is required")
@@ -155,3 +323,15 @@ func validateGetUserByTelegram(req *ssov1.GetUserByTelegramRequest) error {
 	}
 	return nil
 }
+
+func validateQueryAuditLog(req *ssov1.QueryAuditLogRequest) error {
+	if req.GetPageSize() < 0 {
+		return status.Error(codes.InvalidArgument, "page size must not be negative")
+	}
+	if req.GetPageToken() != "" {
+		if _, err := strconv.ParseInt(req.GetPageToken(), 10, 64); err != nil {
+			return status.Error(codes.InvalidArgument, "invalid page token")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,140 @@
+package auth
+
+import (
+	"context"
+	"errors"
+
+	ssov1 "github.com/j0n1que/sso-protos/gen/go"
+	"github.com/j0n1que/sso-service/internal/domain/models"
+	"github.com/j0n1que/sso-service/internal/services/webhooks"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const defaultDeadDeliveriesLimit = 100
+
+type Webhooks interface {
+	RegisterWebhook(ctx context.Context, url string, eventTypes []string) (models.Webhook, error)
+	ListWebhooks(ctx context.Context) ([]models.Webhook, error)
+	DeleteWebhook(ctx context.Context, webhookID string) error
+	ListDeadDeliveries(ctx context.Context, limit int64) ([]models.WebhookDelivery, error)
+	ReplayDelivery(ctx context.Context, deliveryID string) error
+}
+
+func (s *ServerAPI) RegisterWebhook(ctx context.Context, req *ssov1.RegisterWebhookRequest) (*ssov1.RegisterWebhookResponse, error) {
+	if err := validateRegisterWebhook(req); err != nil {
+		return nil, err
+	}
+	webhook, err := s.webhooks.RegisterWebhook(ctx, req.GetUrl(), req.GetEventTypes())
+	if err != nil {
+		if errors.Is(err, webhooks.ErrInvalidURL) || errors.Is(err, webhooks.ErrUnknownEvent) {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+	return &ssov1.RegisterWebhookResponse{
+		Webhook: webhookToProto(webhook),
+		Secret:  webhook.Secret,
+	}, nil
+}
+
+func (s *ServerAPI) ListWebhooks(ctx context.Context, req *emptypb.Empty) (*ssov1.ListWebhooksResponse, error) {
+	registered, err := s.webhooks.ListWebhooks(ctx)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	resp := &ssov1.ListWebhooksResponse{
+		Webhooks: make([]*ssov1.Webhook, len(registered)),
+	}
+	for i, webhook := range registered {
+		resp.Webhooks[i] = webhookToProto(webhook)
+	}
+
+	return resp, nil
+}
+
+func (s *ServerAPI) DeleteWebhook(ctx context.Context, req *ssov1.DeleteWebhookRequest) (*emptypb.Empty, error) {
+	if req.GetWebhookId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "webhook id is required")
+	}
+	if err := s.webhooks.DeleteWebhook(ctx, req.GetWebhookId()); err != nil {
+		if errors.Is(err, webhooks.ErrWebhookNotFound) {
+			return nil, status.Error(codes.NotFound, "webhook not found")
+		}
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+	return &emptypb.Empty{}, nil
+}
+
+func (s *ServerAPI) ListDeadDeliveries(ctx context.Context, req *ssov1.ListDeadDeliveriesRequest) (*ssov1.ListDeadDeliveriesResponse, error) {
+	limit := req.GetLimit()
+	if limit <= 0 {
+		limit = defaultDeadDeliveriesLimit
+	}
+
+	deliveries, err := s.webhooks.ListDeadDeliveries(ctx, limit)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	resp := &ssov1.ListDeadDeliveriesResponse{
+		Deliveries: make([]*ssov1.WebhookDelivery, len(deliveries)),
+	}
+	for i, delivery := range deliveries {
+		history := make([]*ssov1.WebhookDeliveryAttempt, len(delivery.History))
+		for j, attempt := range delivery.History {
+			history[j] = &ssov1.WebhookDeliveryAttempt{
+				At:         timestamppb.New(attempt.At),
+				StatusCode: int32(attempt.StatusCode),
+				Error:      attempt.Error,
+				DurationMs: attempt.Duration.Milliseconds(),
+			}
+		}
+		resp.Deliveries[i] = &ssov1.WebhookDelivery{
+			DeliveryId: delivery.ID,
+			WebhookId:  delivery.WebhookID,
+			EventId:    delivery.Event.ID,
+			EventType:  delivery.Event.Type,
+			UserId:     delivery.Event.UserID,
+			Attempts:   int32(delivery.Attempts),
+			LastError:  delivery.LastError,
+			CreatedAt:  timestamppb.New(delivery.CreatedAt),
+			History:    history,
+		}
+	}
+
+	return resp, nil
+}
+
+func (s *ServerAPI) ReplayDelivery(ctx context.Context, req *ssov1.ReplayDeliveryRequest) (*emptypb.Empty, error) {
+	if req.GetDeliveryId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "delivery id is required")
+	}
+	if err := s.webhooks.ReplayDelivery(ctx, req.GetDeliveryId()); err != nil {
+		if errors.Is(err, webhooks.ErrDeliveryNotFound) {
+			return nil, status.Error(codes.NotFound, "dead delivery not found")
+		}
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+	return &emptypb.Empty{}, nil
+}
+
+func webhookToProto(webhook models.Webhook) *ssov1.Webhook {
+	return &ssov1.Webhook{
+		WebhookId:  webhook.ID,
+		Url:        webhook.URL,
+		EventTypes: webhook.EventTypes,
+		CreatedBy:  webhook.CreatedBy,
+		CreatedAt:  timestamppb.New(webhook.CreatedAt),
+	}
+}
+
+func validateRegisterWebhook(req *ssov1.RegisterWebhookRequest) error {
+	if req.GetUrl() == "" {
+		return status.Error(codes.InvalidArgument, "url is required")
+	}
+	return nil
+}
@@ -0,0 +1,42 @@
+package caller
+
+import "context"
+
+type ctxKey struct{}
+
+// Info describes who is calling the service. UserID is zero for
+// unauthenticated calls and for machine principals authenticated by a client
+// certificate, which set Principal instead.
+type Info struct {
+	UserID    int64
+	Principal string
+	IP        string
+	UserAgent string
+}
+
+func WithInfo(ctx context.Context, info Info) context.Context {
+	return context.WithValue(ctx, ctxKey{}, info)
+}
+
+// WithUserID returns a copy of ctx whose caller info has the given user ID.
+func WithUserID(ctx context.Context, userID int64) context.Context {
+	info := FromContext(ctx)
+	info.UserID = userID
+
+	return WithInfo(ctx, info)
+}
+
+// WithPrincipal returns a copy of ctx whose caller info has the given machine
+// principal.
+func WithPrincipal(ctx context.Context, principal string) context.Context {
+	info := FromContext(ctx)
+	info.Principal = principal
+
+	return WithInfo(ctx, info)
+}
+
+func FromContext(ctx context.Context) Info {
+	info, _ := ctx.Value(ctxKey{}).(Info)
+
+	return info
+}
@@ -1,26 +1,121 @@
 package jwt
 
 import (
-	"os"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/j0n1que/sso-service/internal/config"
 	"github.com/j0n1que/sso-service/internal/domain/models"
 )
 
-func NewToken(user models.User, duration time.Duration) (string, error) {
-	secret := os.Getenv("SECRET")
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+)
+
+var ErrInvalidToken = errors.New("invalid token")
+
+// Claims are the claims of the tokens issued by the service. Roles reflect
+// the user at the time the token was issued.
+type Claims struct {
+	jwt.RegisteredClaims
+	UserID        int64    `json:"uid"`
+	Login         string   `json:"login"`
+	Roles         []string `json:"roles,omitempty"`
+	SessionID     string   `json:"sid,omitempty"`
+	TelegramLogin string   `json:"telegram_login,omitempty"`
+}
+
+// Manager issues and parses the tokens of the service.
+type Manager struct {
+	secret    []byte
+	issuer    string
+	audiences []string
+}
+
+// New fails when the signing key is missing or too short to be safe.
+func New(cfg config.JWTConfig) (*Manager, error) {
+	const op = "jwt.New"
+
+	switch {
+	case cfg.Secret == "":
+		return nil, fmt.Errorf("%s: jwt secret is not set", op)
+	case len(cfg.Secret) < config.MinJWTSecretLength:
+		return nil, fmt.Errorf("%s: jwt secret must be at least %d bytes, got %d", op, config.MinJWTSecretLength, len(cfg.Secret))
+	case cfg.Issuer == "":
+		return nil, fmt.Errorf("%s: jwt issuer is not set", op)
+	case len(cfg.Audiences) == 0:
+		return nil, fmt.Errorf("%s: jwt audiences are not set", op)
+	}
+
+	return &Manager{
+		secret:    []byte(cfg.Secret),
+		issuer:    cfg.Issuer,
+		audiences: cfg.Audiences,
+	}, nil
+}
 
-	token := jwt.New(jwt.SigningMethodHS256)
+// NewToken signs a token for a new session of user.
+func (m *Manager) NewToken(user models.User, duration time.Duration) (string, error) {
+	now := time.Now()
 
-	claims := token.Claims.(jwt.MapClaims)
-	claims["uid"] = user.ID
-	claims["login"] = user.Login
-	claims["exp"] = time.Now().Add(duration)
+	roles := []string{RoleUser}
+	if user.IsAdmin {
+		roles = append(roles, RoleAdmin)
+	}
+
+	claims := Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        uuid.NewString(),
+			Issuer:    m.issuer,
+			Subject:   strconv.FormatInt(user.ID, 10),
+			Audience:  m.audiences,
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(duration)),
+		},
+		UserID:        user.ID,
+		Login:         user.Login,
+		Roles:         roles,
+		SessionID:     uuid.NewString(),
+		TelegramLogin: user.TelegramLogin,
+	}
+
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(m.secret)
+}
+
+// Parse verifies the signature, lifetime, issuer and audience of token and
+// returns its claims. The audience must contain one of the configured ones.
+// Any failure wraps ErrInvalidToken.
+func (m *Manager) Parse(tokenString string) (Claims, error) {
+	var claims Claims
 
-	tokenString, err := token.SignedString([]byte(secret))
+	_, err := jwt.ParseWithClaims(tokenString, &claims, func(*jwt.Token) (interface{}, error) {
+		return m.secret, nil
+	},
+		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithIssuer(m.issuer),
+	)
 	if err != nil {
-		return "", err
+		return Claims{}, fmt.Errorf("%w: %w", ErrInvalidToken, err)
 	}
-	return tokenString, nil
+
+	if !slices.ContainsFunc(claims.Audience, func(aud string) bool {
+		return slices.Contains(m.audiences, aud)
+	}) {
+		return Claims{}, fmt.Errorf("%w: unexpected audience %v", ErrInvalidToken, claims.Audience)
+	}
+
+	if claims.ID == "" || claims.UserID == 0 {
+		return Claims{}, fmt.Errorf("%w: missing jti or uid", ErrInvalidToken)
+	}
+
+	return claims, nil
 }
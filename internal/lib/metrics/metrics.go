@@ -0,0 +1,82 @@
+package metrics
+
+import (
+	"time"
+
+	"github.com/j0n1que/sso-service/internal/storage"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const namespace = "sso"
+
+// Registry holds every metric exposed by the service.
+var Registry = prometheus.NewRegistry()
+
+var factory = promauto.With(Registry)
+
+var (
+	LoginAttempts = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "login_attempts_total",
+		Help:      "Login attempts by outcome and failure reason.",
+	}, []string{"outcome", "reason"})
+
+	ActiveSessions = factory.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "active_sessions",
+		Help:      "Number of users with a stored token.",
+	})
+
+	PasswordHashDuration = factory.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "password_hash_duration_seconds",
+		Help:      "Time spent hashing and comparing passwords.",
+		Buckets:   []float64{.01, .025, .05, .1, .25, .5, 1, 2.5},
+	}, []string{"algorithm", "operation"})
+
+	StorageDuration = factory.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "storage_operation_duration_seconds",
+		Help:      "Latency of storage operations.",
+		Buckets:   prometheus.ExponentialBuckets(.0005, 2, 14),
+	}, []string{"storage", "operation"})
+
+	StorageErrors = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "storage_operation_errors_total",
+		Help:      "Storage operations that failed unexpectedly.",
+	}, []string{"storage", "operation"})
+
+	CacheRequests = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "cache_requests_total",
+		Help:      "Cache lookups by cache and result, hit or miss.",
+	}, []string{"cache", "result"})
+
+	CacheInvalidationErrors = factory.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "cache_invalidation_errors_total",
+		Help:      "Cache entries that could not be dropped and stay until they expire.",
+	})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+}
+
+// ObserveStorage records the latency and the outcome of a storage operation
+// started at start. It is meant to be deferred with a pointer to the named
+// error result of the operation.
+func ObserveStorage(storageName, operation string, start time.Time, err *error) {
+	StorageDuration.WithLabelValues(storageName, operation).Observe(time.Since(start).Seconds())
+
+	if *err == nil || storage.IsExpected(*err) {
+		return
+	}
+	StorageErrors.WithLabelValues(storageName, operation).Inc()
+}
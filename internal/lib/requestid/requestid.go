@@ -0,0 +1,55 @@
+// Package requestid carries the ID correlating the logs of a request, taken
+// from the x-request-id sent by the client or generated when it is missing.
+package requestid
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+)
+
+// MetadataKey is the gRPC metadata key of the request ID. The gateway reads
+// and writes it as the X-Request-Id header.
+const MetadataKey = "x-request-id"
+
+// maxLength bounds the accepted IDs, so that clients cannot bloat every log
+// line of their requests.
+const maxLength = 128
+
+type ctxKey struct{}
+
+func With(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, ctxKey{}, id)
+}
+
+// FromContext returns the request ID of ctx, or an empty string.
+func FromContext(ctx context.Context) string {
+	id, _ := ctx.Value(ctxKey{}).(string)
+
+	return id
+}
+
+// OrNew returns id if it is a usable request ID, or a new one otherwise.
+func OrNew(id string) string {
+	if valid(id) {
+		return id
+	}
+
+	return uuid.NewString()
+}
+
+// valid accepts non-empty printable ASCII without spaces, which keeps IDs
+// safe to log as they are.
+func valid(id string) bool {
+	if id == "" || len(id) > maxLength {
+		return false
+	}
+
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+
+	return true
+}
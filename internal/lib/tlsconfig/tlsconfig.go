@@ -0,0 +1,173 @@
+package tlsconfig
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/j0n1que/sso-service/internal/config"
+)
+
+// checkInterval limits how often the files are inspected for changes.
+const checkInterval = 10 * time.Second
+
+var versions = map[string]uint16{
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// Reloader serves a TLS configuration built from certificate files and
+// rebuilds it when the files change on disk. A broken update is logged and
+// the previous configuration keeps being served.
+type Reloader struct {
+	log               *slog.Logger
+	certFile          string
+	keyFile           string
+	clientCAFile      string
+	requireClientCert bool
+	minVersion        uint16
+
+	mu        sync.Mutex
+	current   *tls.Config
+	stamp     string
+	checkedAt time.Time
+}
+
+func New(log *slog.Logger, cfg config.TLSConfig) (*Reloader, error) {
+	const op = "tlsconfig.New"
+
+	minVersion, ok := versions[cfg.MinVersion]
+	if !ok {
+		return nil, fmt.Errorf("%s: unsupported min TLS version %q", op, cfg.MinVersion)
+	}
+
+	if cfg.CertFile == "" || cfg.KeyFile == "" {
+		return nil, fmt.Errorf("%s: both certificate and key files are required", op)
+	}
+
+	r := &Reloader{
+		log:               log,
+		certFile:          cfg.CertFile,
+		keyFile:           cfg.KeyFile,
+		clientCAFile:      cfg.ClientCAFile,
+		requireClientCert: cfg.RequireClientCert,
+		minVersion:        minVersion,
+	}
+
+	stamp, err := r.fileStamp()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	current, err := r.load()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	r.current = current
+	r.stamp = stamp
+	r.checkedAt = time.Now()
+
+	return r, nil
+}
+
+// Config returns a server configuration that resolves the current
+// certificates on every handshake.
+func (r *Reloader) Config() *tls.Config {
+	return &tls.Config{
+		MinVersion:         r.minVersion,
+		GetConfigForClient: r.configForClient,
+	}
+}
+
+func (r *Reloader) configForClient(*tls.ClientHelloInfo) (*tls.Config, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if time.Since(r.checkedAt) < checkInterval {
+		return r.current, nil
+	}
+	r.checkedAt = time.Now()
+
+	stamp, err := r.fileStamp()
+	if err != nil {
+		r.log.Error("failed to check TLS files", slog.String("error", err.Error()))
+		return r.current, nil
+	}
+	if stamp == r.stamp {
+		return r.current, nil
+	}
+
+	next, err := r.load()
+	if err != nil {
+		r.log.Error("failed to reload TLS certificates, keeping the previous ones", slog.String("error", err.Error()))
+		return r.current, nil
+	}
+
+	r.current = next
+	r.stamp = stamp
+	r.log.Info("TLS certificates reloaded")
+
+	return r.current, nil
+}
+
+func (r *Reloader) load() (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := &tls.Config{
+		MinVersion:   r.minVersion,
+		Certificates: []tls.Certificate{cert},
+		ClientAuth:   tls.NoClientCert,
+	}
+
+	if r.clientCAFile == "" {
+		return cfg, nil
+	}
+
+	pem, err := os.ReadFile(r.clientCAFile)
+	if err != nil {
+		return nil, err
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, errors.New("no certificates found in client CA file")
+	}
+
+	cfg.ClientCAs = pool
+	cfg.ClientAuth = tls.VerifyClientCertIfGiven
+	if r.requireClientCert {
+		cfg.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+
+	return cfg, nil
+}
+
+// fileStamp summarizes the modification times and sizes of the watched files.
+func (r *Reloader) fileStamp() (string, error) {
+	var b strings.Builder
+
+	for _, path := range []string{r.certFile, r.keyFile, r.clientCAFile} {
+		if path == "" {
+			continue
+		}
+
+		info, err := os.Stat(path)
+		if err != nil {
+			return "", err
+		}
+
+		fmt.Fprintf(&b, "%s:%d:%d;", path, info.ModTime().UnixNano(), info.Size())
+	}
+
+	return b.String(), nil
+}
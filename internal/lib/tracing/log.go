@@ -0,0 +1,66 @@
+package tracing
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/j0n1que/sso-service/internal/lib/requestid"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// LogHandler adds the request ID and the trace and span IDs of the span in
+// the record context to every record.
+type LogHandler struct {
+	slog.Handler
+}
+
+func NewLogHandler(h slog.Handler) *LogHandler {
+	return &LogHandler{Handler: h}
+}
+
+func (h *LogHandler) Handle(ctx context.Context, r slog.Record) error {
+	r.AddAttrs(correlation(ctx)...)
+
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h *LogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &LogHandler{Handler: h.Handler.WithAttrs(attrs)}
+}
+
+func (h *LogHandler) WithGroup(name string) slog.Handler {
+	return &LogHandler{Handler: h.Handler.WithGroup(name)}
+}
+
+// Logger returns log annotated with the request ID and the trace and span
+// IDs of the span in ctx, for code that logs without passing a context.
+func Logger(ctx context.Context, log *slog.Logger) *slog.Logger {
+	attrs := correlation(ctx)
+	if len(attrs) == 0 {
+		return log
+	}
+
+	args := make([]any, len(attrs))
+	for i, attr := range attrs {
+		args[i] = attr
+	}
+
+	return log.With(args...)
+}
+
+func correlation(ctx context.Context) []slog.Attr {
+	var attrs []slog.Attr
+
+	if id := requestid.FromContext(ctx); id != "" {
+		attrs = append(attrs, slog.String("request_id", id))
+	}
+
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		attrs = append(attrs,
+			slog.String("trace_id", sc.TraceID().String()),
+			slog.String("span_id", sc.SpanID().String()),
+		)
+	}
+
+	return attrs
+}
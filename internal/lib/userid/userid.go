@@ -0,0 +1,26 @@
+// Package userid generates user IDs.
+//
+// Users created before this package existed got truncated 32-bit random IDs.
+// Those IDs stay valid as they are: new IDs are drawn from the range above
+// 32 bits, so they never collide with an existing one, and stay below 2^53 so
+// that clients parsing IDs as JSON numbers do not lose precision.
+package userid
+
+import "math/rand/v2"
+
+const (
+	// Min is the smallest generated ID. Smaller IDs are legacy ones.
+	Min int64 = 1 << 32
+	// Max bounds generated IDs from above.
+	Max int64 = 1 << 53
+
+	// MaxAttempts bounds how many IDs storages try before giving up on a
+	// collision.
+	MaxAttempts = 5
+)
+
+// New returns a random ID in [Min, Max). Storages insert it only if it is not
+// taken yet and retry with a fresh ID otherwise.
+func New() int64 {
+	return Min + rand.Int64N(Max-Min)
+}
@@ -0,0 +1,159 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/j0n1que/sso-service/internal/domain/models"
+	"github.com/j0n1que/sso-service/internal/lib/caller"
+	"github.com/j0n1que/sso-service/internal/lib/metrics"
+	"github.com/j0n1que/sso-service/internal/lib/tracing"
+	"github.com/j0n1que/sso-service/internal/storage"
+)
+
+const (
+	defaultAuditPageSize = 50
+	maxAuditPageSize     = 500
+
+	// auditTimeout bounds audit writes, which outlive the deadline of the
+	// call so that failures caused by it are still recorded.
+	auditTimeout = 5 * time.Second
+)
+
+// auditReasons are the errors whose text is safe to store as a failure reason,
+// anything else is recorded as an internal error.
+var auditReasons = []error{
+	ErrInvalidCredentials,
+	ErrUserExists,
+	ErrTokenExists,
+	ErrUserNotFound,
+	ErrUserDisabled,
+	ErrConflict,
+	ErrInvalidToken,
+	storage.ErrTokenExists,
+	context.DeadlineExceeded,
+}
+
+// QueryAuditLog returns a page of audit entries matching the filter, newest
+// first, and the sequence number to continue from or zero on the last page.
+func (a *Auth) QueryAuditLog(ctx context.Context, filter models.AuditFilter) ([]models.AuditEntry, int64, error) {
+	const op = "auth.QueryAuditLog"
+
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+
+	log := tracing.Logger(ctx, a.log).With(
+		slog.String("op", op),
+	)
+
+	log.Info("querying audit log")
+
+	if filter.Limit <= 0 {
+		filter.Limit = defaultAuditPageSize
+	}
+	if filter.Limit > maxAuditPageSize {
+		filter.Limit = maxAuditPageSize
+	}
+
+	entries, err := a.auditLog.Query(ctx, filter)
+	if err != nil {
+		log.Error("failed to query audit log", slog.String("error", err.Error()))
+
+		return nil, 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	var next int64
+	if int64(len(entries)) == filter.Limit {
+		next = entries[len(entries)-1].Seq
+	}
+
+	log.Info("audit log queried", slog.Int("count", len(entries)))
+
+	return entries, next, nil
+}
+
+// VerifyAuditLog checks the integrity of the audit log. It returns the number
+// of intact entries and the sequence number of the first tampered entry, or
+// zero if the whole log is intact.
+func (a *Auth) VerifyAuditLog(ctx context.Context) (int64, int64, error) {
+	const op = "auth.VerifyAuditLog"
+
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+
+	log := tracing.Logger(ctx, a.log).With(
+		slog.String("op", op),
+	)
+
+	log.Info("verifying audit log")
+
+	checked, brokenAt, err := a.auditLog.Verify(ctx)
+	if err != nil {
+		log.Error("failed to verify audit log", slog.String("error", err.Error()))
+
+		return 0, 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if brokenAt != 0 {
+		log.Warn("audit log is tampered", slog.Int64("seq", brokenAt))
+	} else {
+		log.Info("audit log is intact", slog.Int64("checked", checked))
+	}
+
+	return checked, brokenAt, nil
+}
+
+// audit appends an entry to the audit log. A nil err records a success.
+// Failures to write the audit log are logged and do not fail the operation.
+func (a *Auth) audit(ctx context.Context, action string, targetID int64, err error) {
+	info := caller.FromContext(ctx)
+
+	entry := models.AuditEntry{
+		Timestamp: time.Now(),
+		ActorID:   info.UserID,
+		TargetID:  targetID,
+		Action:    action,
+		Outcome:   models.AuditOutcomeSuccess,
+		IP:        info.IP,
+		UserAgent: info.UserAgent,
+	}
+
+	if err != nil {
+		entry.Outcome = models.AuditOutcomeFailure
+		entry.Reason = auditReason(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), auditTimeout)
+	defer cancel()
+
+	if err := a.auditLog.Append(ctx, entry); err != nil {
+		a.log.Error("failed to write audit log",
+			slog.String("action", action),
+			slog.Int64("target_id", targetID),
+			slog.String("error", err.Error()),
+		)
+	}
+}
+
+// recordLogin audits a login attempt and counts it in the login metrics.
+func (a *Auth) recordLogin(ctx context.Context, userID int64, err error) {
+	a.audit(ctx, models.AuditActionLogin, userID, err)
+
+	if err != nil {
+		metrics.LoginAttempts.WithLabelValues(models.AuditOutcomeFailure, auditReason(err)).Inc()
+		return
+	}
+	metrics.LoginAttempts.WithLabelValues(models.AuditOutcomeSuccess, "").Inc()
+}
+
+func auditReason(err error) string {
+	for _, reason := range auditReasons {
+		if errors.Is(err, reason) {
+			return reason.Error()
+		}
+	}
+	return "internal error"
+}
@@ -5,31 +5,49 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"sync/atomic"
 	"time"
 
 	ssov1 "github.com/j0n1que/sso-protos/gen/go"
 	"github.com/j0n1que/sso-service/internal/domain/models"
 	"github.com/j0n1que/sso-service/internal/lib/jwt"
+	"github.com/j0n1que/sso-service/internal/lib/tracing"
 	"github.com/j0n1que/sso-service/internal/storage"
-	"golang.org/x/crypto/bcrypt"
 )
 
 type Auth struct {
 	log         *slog.Logger
+	tokens      TokenIssuer
 	usrChanger  UserChanger
 	usrProvider UserProvider
 	tknProvider TokenProvider
-	tokenTTL    time.Duration
+	auditLog    AuditLog
+	outbox      EventOutbox
+	txManager   Transactor
+	ttls        atomic.Pointer[ttlConfig]
+}
+
+// ttls are kept together so that a reload replaces them at once.
+type ttlConfig struct {
+	token         time.Duration
+	userRetention time.Duration
 }
 
 type UserChanger interface {
-	SaveUser(ctx context.Context, user models.User) error
+	SaveUser(ctx context.Context, user models.User) (int64, error)
 	ChangePassword(ctx context.Context, userID int64, newPasswordHash []byte) error
 	MakeAdmin(ctx context.Context, userID int64) error
+	RevokeAdmin(ctx context.Context, userID int64) error
+	DisableUser(ctx context.Context, userID int64) error
+	EnableUser(ctx context.Context, userID int64) error
+	DeleteUser(ctx context.Context, userID int64) error
+	PurgeDeletedUsers(ctx context.Context, before time.Time) (int64, error)
+	EraseUser(ctx context.Context, userID int64) error
 }
 
 type UserProvider interface {
 	User(ctx context.Context, login string) (models.User, error)
+	UserByID(ctx context.Context, userID int64) (models.User, error)
 	IsAdmin(ctx context.Context, userID int64) (bool, error)
 	GetAllUsers(ctx context.Context) ([]models.User, error)
 	GetUserByTelegram(ctx context.Context, telegramLogin string) ([]models.User, error)
@@ -37,8 +55,31 @@ type UserProvider interface {
 
 type TokenProvider interface {
 	JWT(ctx context.Context, userID int64) (string, error)
+	JWTTTL(ctx context.Context, userID int64) (time.Duration, error)
 	SaveJWT(ctx context.Context, token string, userID int64, ttl time.Duration) error
 	DeleteJWT(ctx context.Context, userID int64) error
+	RevokeToken(ctx context.Context, jti string, ttl time.Duration) error
+	IsTokenRevoked(ctx context.Context, jti string) (bool, error)
+}
+
+type TokenIssuer interface {
+	NewToken(user models.User, duration time.Duration) (string, error)
+	Parse(token string) (jwt.Claims, error)
+}
+
+type AuditLog interface {
+	Append(ctx context.Context, entry models.AuditEntry) error
+	Query(ctx context.Context, filter models.AuditFilter) ([]models.AuditEntry, error)
+	Verify(ctx context.Context) (int64, int64, error)
+}
+
+type EventOutbox interface {
+	Add(ctx context.Context, events ...models.Event) error
+	EraseUser(ctx context.Context, userID int64) error
+}
+
+type Transactor interface {
+	WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error
 }
 
 var (
@@ -46,29 +87,48 @@ var (
 	ErrUserExists         = errors.New("user already exists")
 	ErrTokenExists        = errors.New("token for that user already exists")
 	ErrUserNotFound       = errors.New("user not found")
+	ErrUserDisabled       = errors.New("user is disabled")
+	ErrConflict           = errors.New("user was modified concurrently")
+	ErrInvalidToken       = errors.New("invalid token")
+	ErrTokenRevoked       = errors.New("token is revoked")
 )
 
-func New(log *slog.Logger, userChanger UserChanger, userProvider UserProvider, tokenProvider TokenProvider, tokenTTL time.Duration) *Auth {
-	return &Auth{
+func New(log *slog.Logger, tokens TokenIssuer, userChanger UserChanger, userProvider UserProvider, tokenProvider TokenProvider, auditLog AuditLog, outbox EventOutbox, txManager Transactor, tokenTTL, userRetention time.Duration) *Auth {
+	a := &Auth{
 		log:         log,
+		tokens:      tokens,
 		usrChanger:  userChanger,
 		usrProvider: userProvider,
 		tknProvider: tokenProvider,
-		tokenTTL:    tokenTTL,
+		auditLog:    auditLog,
+		outbox:      outbox,
+		txManager:   txManager,
 	}
+	a.SetTTLs(tokenTTL, userRetention)
+
+	return a
+}
+
+// SetTTLs changes the lifetime of new tokens and how long deleted users are
+// kept before they are purged.
+func (a *Auth) SetTTLs(tokenTTL, userRetention time.Duration) {
+	a.ttls.Store(&ttlConfig{token: tokenTTL, userRetention: userRetention})
 }
 
 func (a *Auth) RegisterUser(ctx context.Context, login, password, telegramLogin string) error {
 	const op = "auth.RegisterUser"
 
-	log := a.log.With(
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+
+	log := tracing.Logger(ctx, a.log).With(
 		slog.String("op", op),
 		slog.String("login", login),
 	)
 
 	log.Info("registering user")
 
-	passHash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	passHash, err := hashPassword(ctx, password)
 	if err != nil {
 		log.Error("failed to generate password hash", slog.String("error", err.Error()))
 
@@ -80,20 +140,37 @@ func (a *Auth) RegisterUser(ctx context.Context, login, password, telegramLogin
 		PassHash:      passHash,
 		IsAdmin:       false,
 		TelegramLogin: telegramLogin,
+		Status:        models.UserStatusActive,
 	}
 
-	if err := a.usrChanger.SaveUser(ctx, user); err != nil {
+	var userID int64
+	err = a.txManager.WithTransaction(ctx, func(ctx context.Context) error {
+		var err error
+		if userID, err = a.usrChanger.SaveUser(ctx, user); err != nil {
+			return err
+		}
+
+		event := a.newEvent(ctx, models.EventUserRegistered, userID)
+		event.Login = user.Login
+		event.TelegramLogin = user.TelegramLogin
+
+		return a.outbox.Add(ctx, event)
+	})
+	if err != nil {
 		if errors.Is(err, storage.ErrUserExists) {
 			log.Warn("user already exists", slog.String("error", err.Error()))
+			a.audit(ctx, models.AuditActionRegister, 0, ErrUserExists)
 
 			return fmt.Errorf("%s: %w", op, ErrUserExists)
 		}
 		log.Error("failed to save user", slog.String("error", err.Error()))
+		a.audit(ctx, models.AuditActionRegister, 0, err)
 
 		return fmt.Errorf("%s: %w", op, err)
 	}
 
 	log.Info("user registered")
+	a.audit(ctx, models.AuditActionRegister, userID, nil)
 
 	return nil
 }
@@ -101,7 +178,10 @@ func (a *Auth) RegisterUser(ctx context.Context, login, password, telegramLogin
 func (a *Auth) AuthorizeUser(ctx context.Context, login, password string) (string, error) {
 	const op = "auth.AuthorizeUser"
 
-	log := a.log.With(
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+
+	log := tracing.Logger(ctx, a.log).With(
 		slog.String("op", op),
 		slog.String("login", login),
 	)
@@ -112,44 +192,86 @@ func (a *Auth) AuthorizeUser(ctx context.Context, login, password string) (strin
 	if err != nil {
 		if errors.Is(err, storage.ErrUserNotFound) {
 			log.Warn("user not found", slog.String("error", err.Error()))
+			a.recordLogin(ctx, 0, ErrInvalidCredentials)
 
 			return "", fmt.Errorf("%s: %w", op, ErrInvalidCredentials)
 		}
 
 		log.Error("failed to get user", slog.String("error", err.Error()))
+		a.recordLogin(ctx, 0, err)
 
 		return "", fmt.Errorf("%s: %w", op, err)
 	}
 
-	if err := bcrypt.CompareHashAndPassword(user.PassHash, []byte(password)); err != nil {
+	if err := comparePassword(ctx, user.PassHash, password); err != nil {
 		log.Info("invalid credentials", slog.String("error", err.Error()))
+		a.recordLogin(ctx, user.ID, ErrInvalidCredentials)
+
+		return "", fmt.Errorf("%s: %w", op, ErrInvalidCredentials)
+	}
+
+	switch {
+	case user.Status == models.UserStatusDeleted:
+		log.Warn("user is deleted")
+		a.recordLogin(ctx, user.ID, ErrInvalidCredentials)
 
 		return "", fmt.Errorf("%s: %w", op, ErrInvalidCredentials)
+	case !user.Active():
+		log.Warn("user is disabled")
+		a.recordLogin(ctx, user.ID, ErrUserDisabled)
+
+		return "", fmt.Errorf("%s: %w", op, ErrUserDisabled)
 	}
 
 	log.Info("user authorized successfully")
 
-	token, err := jwt.NewToken(user, a.tokenTTL)
+	tokenTTL := a.ttls.Load().token
+
+	token, err := a.tokens.NewToken(user, tokenTTL)
 	if err != nil {
 		log.Error("failed to generate token", slog.String("error", err.Error()))
 		return "", fmt.Errorf("%s: %w", op, err)
 	}
-	if err := a.tknProvider.SaveJWT(ctx, token, user.ID, a.tokenTTL); err != nil {
+
+	saved := false
+	err = a.withEvent(ctx, models.EventUserLoggedIn, user.ID, func(ctx context.Context) error {
+		if err := a.tknProvider.SaveJWT(ctx, token, user.ID, tokenTTL); err != nil {
+			return err
+		}
+		saved = true
+
+		return nil
+	})
+	if err != nil {
 		if errors.Is(err, storage.ErrTokenExists) {
 			log.Warn("token for that user already exists", slog.String("error", err.Error()))
 		}
 		log.Error("failed to save token", slog.String("error", err.Error()))
 
+		// the token store is outside the transaction, so the session is
+		// undone by hand for the login to be retried
+		if saved {
+			if err := a.tknProvider.DeleteJWT(ctx, user.ID); err != nil {
+				log.Error("failed to undo session", slog.String("error", err.Error()))
+			}
+		}
+		a.recordLogin(ctx, user.ID, err)
+
 		return "", fmt.Errorf("%s: %w", op, err)
 	}
 
+	a.recordLogin(ctx, user.ID, nil)
+
 	return token, nil
 }
 
 func (a *Auth) IsAdmin(ctx context.Context, userID int64) (bool, error) {
 	const op = "auth.IsAdmin"
 
-	log := a.log.With(
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+
+	log := tracing.Logger(ctx, a.log).With(
 		slog.String("op", op),
 		slog.Int64("user_id", userID),
 	)
@@ -170,32 +292,41 @@ func (a *Auth) IsAdmin(ctx context.Context, userID int64) (bool, error) {
 func (a *Auth) ChangePassword(ctx context.Context, userID int64, newPassword string) error {
 	const op = "auth.ChangePassword"
 
-	log := a.log.With(
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+
+	log := tracing.Logger(ctx, a.log).With(
 		slog.String("op", op),
 		slog.Int64("user_id", userID),
 	)
 
 	log.Info("changing user's password")
 
-	newPassHash, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
+	newPassHash, err := hashPassword(ctx, newPassword)
 	if err != nil {
 		log.Error("failed to generate new password hash", slog.String("error", err.Error()))
 
 		return fmt.Errorf("%s: %w", op, err)
 	}
 
-	if err := a.usrChanger.ChangePassword(ctx, userID, newPassHash); err != nil {
+	err = a.withEvent(ctx, models.EventUserPasswordChanged, userID, func(ctx context.Context) error {
+		return a.usrChanger.ChangePassword(ctx, userID, newPassHash)
+	})
+	if err != nil {
 		if errors.Is(err, storage.ErrUserNotFound) {
 			log.Warn("user not found", slog.String("error", err.Error()))
+			a.audit(ctx, models.AuditActionChangePassword, userID, ErrUserNotFound)
 
 			return fmt.Errorf("%s: %w", op, ErrUserNotFound)
 		}
 		log.Error("failed to change user's password", slog.String("error", err.Error()))
+		a.audit(ctx, models.AuditActionChangePassword, userID, err)
 
 		return fmt.Errorf("%s: %w", op, err)
 	}
 
 	log.Info("user's password changed")
+	a.audit(ctx, models.AuditActionChangePassword, userID, nil)
 
 	return nil
 }
@@ -203,7 +334,10 @@ func (a *Auth) ChangePassword(ctx context.Context, userID int64, newPassword str
 func (a *Auth) GetUserByTelegram(ctx context.Context, telegramLogin string) ([]*ssov1.User, error) {
 	const op = "auth.GetUserByTelegram"
 
-	log := a.log.With(
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+
+	log := tracing.Logger(ctx, a.log).With(
 		slog.String("op", op),
 		slog.String("telegram_login", telegramLogin),
 	)
@@ -235,6 +369,7 @@ func (a *Auth) GetUserByTelegram(ctx context.Context, telegramLogin string) ([]*
 			Password:      string(user.PassHash),
 			IsAdmin:       user.IsAdmin,
 			TelegramLogin: user.TelegramLogin,
+			Status:        user.Status,
 		}
 	}
 
@@ -244,7 +379,10 @@ func (a *Auth) GetUserByTelegram(ctx context.Context, telegramLogin string) ([]*
 func (a *Auth) GetAllUsers(ctx context.Context) ([]*ssov1.User, error) {
 	const op = "auth.GetAllUsers"
 
-	log := a.log.With(
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+
+	log := tracing.Logger(ctx, a.log).With(
 		slog.String("op", op),
 	)
 
@@ -267,6 +405,7 @@ func (a *Auth) GetAllUsers(ctx context.Context) ([]*ssov1.User, error) {
 			Login:    user.Login,
 			Password: string(user.PassHash),
 			IsAdmin:  user.IsAdmin,
+			Status:   user.Status,
 		}
 	}
 
@@ -276,25 +415,226 @@ func (a *Auth) GetAllUsers(ctx context.Context) ([]*ssov1.User, error) {
 func (a *Auth) MakeAdmin(ctx context.Context, userID int64) error {
 	const op = "auth.MakeAdmin"
 
-	log := a.log.With(
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+
+	log := tracing.Logger(ctx, a.log).With(
 		slog.String("op", op),
 		slog.Int64("user_id", userID),
 	)
 
 	log.Info("making user an admin")
 
-	if err := a.usrChanger.MakeAdmin(ctx, userID); err != nil {
+	err := a.withEvent(ctx, models.EventUserPromoted, userID, func(ctx context.Context) error {
+		return a.usrChanger.MakeAdmin(ctx, userID)
+	})
+	if err != nil {
 		if errors.Is(err, storage.ErrUserNotFound) {
 			log.Warn("user not found", slog.String("error", err.Error()))
+			a.audit(ctx, models.AuditActionMakeAdmin, userID, ErrUserNotFound)
 
 			return fmt.Errorf("%s: %w", op, ErrUserNotFound)
 		}
 		log.Error("failed to make user an admin", slog.String("error", err.Error()))
+		a.audit(ctx, models.AuditActionMakeAdmin, userID, err)
 
 		return fmt.Errorf("%s: %w", op, err)
 	}
 
 	log.Info("successfully made user an admin")
+	a.audit(ctx, models.AuditActionMakeAdmin, userID, nil)
+
+	return nil
+}
+
+func (a *Auth) RevokeAdmin(ctx context.Context, userID int64) error {
+	const op = "auth.RevokeAdmin"
+
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+
+	log := tracing.Logger(ctx, a.log).With(
+		slog.String("op", op),
+		slog.Int64("user_id", userID),
+	)
+
+	log.Info("revoking admin rights")
+
+	err := a.withEvent(ctx, models.EventUserDemoted, userID, func(ctx context.Context) error {
+		return a.usrChanger.RevokeAdmin(ctx, userID)
+	})
+	if err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			log.Warn("user not found", slog.String("error", err.Error()))
+			a.audit(ctx, models.AuditActionRevokeAdmin, userID, ErrUserNotFound)
+
+			return fmt.Errorf("%s: %w", op, ErrUserNotFound)
+		}
+		log.Error("failed to revoke admin rights", slog.String("error", err.Error()))
+		a.audit(ctx, models.AuditActionRevokeAdmin, userID, err)
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("successfully revoked admin rights")
+	a.audit(ctx, models.AuditActionRevokeAdmin, userID, nil)
+
+	return nil
+}
+
+func (a *Auth) DisableUser(ctx context.Context, userID int64) error {
+	const op = "auth.DisableUser"
+
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+
+	log := tracing.Logger(ctx, a.log).With(
+		slog.String("op", op),
+		slog.Int64("user_id", userID),
+	)
+
+	log.Info("disabling user")
+
+	err := a.withEvent(ctx, models.EventUserDisabled, userID, func(ctx context.Context) error {
+		return a.usrChanger.DisableUser(ctx, userID)
+	})
+	if err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			log.Warn("user not found", slog.String("error", err.Error()))
+			a.audit(ctx, models.AuditActionDisableUser, userID, ErrUserNotFound)
+
+			return fmt.Errorf("%s: %w", op, ErrUserNotFound)
+		}
+		if errors.Is(err, storage.ErrConflict) {
+			log.Warn("user was modified concurrently", slog.String("error", err.Error()))
+			a.audit(ctx, models.AuditActionDisableUser, userID, ErrConflict)
+
+			return fmt.Errorf("%s: %w", op, ErrConflict)
+		}
+		log.Error("failed to disable user", slog.String("error", err.Error()))
+		a.audit(ctx, models.AuditActionDisableUser, userID, err)
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := a.endSession(ctx, userID); err != nil {
+		log.Error("failed to revoke user's session", slog.String("error", err.Error()))
+		a.audit(ctx, models.AuditActionDisableUser, userID, err)
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("successfully disabled user")
+	a.audit(ctx, models.AuditActionDisableUser, userID, nil)
+
+	return nil
+}
+
+func (a *Auth) EnableUser(ctx context.Context, userID int64) error {
+	const op = "auth.EnableUser"
+
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+
+	log := tracing.Logger(ctx, a.log).With(
+		slog.String("op", op),
+		slog.Int64("user_id", userID),
+	)
+
+	log.Info("enabling user")
+
+	err := a.withEvent(ctx, models.EventUserEnabled, userID, func(ctx context.Context) error {
+		return a.usrChanger.EnableUser(ctx, userID)
+	})
+	if err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			log.Warn("user not found", slog.String("error", err.Error()))
+			a.audit(ctx, models.AuditActionEnableUser, userID, ErrUserNotFound)
+
+			return fmt.Errorf("%s: %w", op, ErrUserNotFound)
+		}
+		log.Error("failed to enable user", slog.String("error", err.Error()))
+		a.audit(ctx, models.AuditActionEnableUser, userID, err)
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("successfully enabled user")
+	a.audit(ctx, models.AuditActionEnableUser, userID, nil)
+
+	return nil
+}
+
+func (a *Auth) DeleteUser(ctx context.Context, userID int64) error {
+	const op = "auth.DeleteUser"
+
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+
+	log := tracing.Logger(ctx, a.log).With(
+		slog.String("op", op),
+		slog.Int64("user_id", userID),
+	)
+
+	log.Info("deleting user")
+
+	err := a.withEvent(ctx, models.EventUserDeleted, userID, func(ctx context.Context) error {
+		return a.usrChanger.DeleteUser(ctx, userID)
+	})
+	if err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			log.Warn("user not found", slog.String("error", err.Error()))
+			a.audit(ctx, models.AuditActionDeleteUser, userID, ErrUserNotFound)
+
+			return fmt.Errorf("%s: %w", op, ErrUserNotFound)
+		}
+		if errors.Is(err, storage.ErrConflict) {
+			log.Warn("user was modified concurrently", slog.String("error", err.Error()))
+			a.audit(ctx, models.AuditActionDeleteUser, userID, ErrConflict)
+
+			return fmt.Errorf("%s: %w", op, ErrConflict)
+		}
+		log.Error("failed to delete user", slog.String("error", err.Error()))
+		a.audit(ctx, models.AuditActionDeleteUser, userID, err)
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := a.endSession(ctx, userID); err != nil {
+		log.Error("failed to revoke user's session", slog.String("error", err.Error()))
+		a.audit(ctx, models.AuditActionDeleteUser, userID, err)
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("successfully deleted user", slog.Duration("retention", a.ttls.Load().userRetention))
+	a.audit(ctx, models.AuditActionDeleteUser, userID, nil)
+
+	return nil
+}
+
+// PurgeDeletedUsers permanently removes users whose retention window after
+// soft deletion has passed.
+func (a *Auth) PurgeDeletedUsers(ctx context.Context) error {
+	const op = "auth.PurgeDeletedUsers"
+
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+
+	log := tracing.Logger(ctx, a.log).With(
+		slog.String("op", op),
+	)
+
+	purged, err := a.usrChanger.PurgeDeletedUsers(ctx, time.Now().Add(-a.ttls.Load().userRetention))
+	if err != nil {
+		log.Error("failed to purge deleted users", slog.String("error", err.Error()))
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if purged > 0 {
+		log.Info("purged deleted users", slog.Int64("count", purged))
+	}
 
 	return nil
 }
@@ -302,7 +642,10 @@ func (a *Auth) MakeAdmin(ctx context.Context, userID int64) error {
 func (a *Auth) GetJWT(ctx context.Context, userID int64) (string, error) {
 	const op = "auth.GetJWT"
 
-	log := a.log.With(
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+
+	log := tracing.Logger(ctx, a.log).With(
 		slog.String("op", op),
 		slog.Int64("user_id", userID),
 	)
@@ -329,25 +672,42 @@ func (a *Auth) GetJWT(ctx context.Context, userID int64) (string, error) {
 func (a *Auth) DeleteJWT(ctx context.Context, userID int64) error {
 	const op = "auth.DeleteJWT"
 
-	log := a.log.With(
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+
+	log := tracing.Logger(ctx, a.log).With(
 		slog.String("op", op),
 		slog.Int64("user_id", userID),
 	)
 
 	log.Info("deleting token")
 
-	if err := a.tknProvider.DeleteJWT(ctx, userID); err != nil {
+	// the token stays valid for bearer authentication until it is revoked
+	if err := a.revokeStored(ctx, userID); err != nil {
+		log.Error("failed to revoke token", slog.String("error", err.Error()))
+		a.audit(ctx, models.AuditActionDeleteToken, userID, err)
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	err := a.withEvent(ctx, models.EventUserLoggedOut, userID, func(ctx context.Context) error {
+		return a.tknProvider.DeleteJWT(ctx, userID)
+	})
+	if err != nil {
 		if errors.Is(err, storage.ErrUserNotFound) {
 			log.Warn("user not found", slog.String("error", err.Error()))
+			a.audit(ctx, models.AuditActionDeleteToken, userID, ErrUserNotFound)
 
 			return fmt.Errorf("%s: %w", op, ErrUserNotFound)
 		}
 		log.Error("failed to delete token", slog.String("error", err.Error()))
+		a.audit(ctx, models.AuditActionDeleteToken, userID, err)
 
 		return fmt.Errorf("%s: %w", op, err)
 	}
 
 	log.Info("successfully deleted token")
+	a.audit(ctx, models.AuditActionDeleteToken, userID, nil)
 
 	return nil
 }
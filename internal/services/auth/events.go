@@ -0,0 +1,32 @@
+package auth
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/j0n1que/sso-service/internal/domain/models"
+	"github.com/j0n1que/sso-service/internal/lib/caller"
+)
+
+func (a *Auth) newEvent(ctx context.Context, eventType string, userID int64) models.Event {
+	return models.Event{
+		ID:         uuid.NewString(),
+		Type:       eventType,
+		UserID:     userID,
+		ActorID:    caller.FromContext(ctx).UserID,
+		OccurredAt: time.Now().UTC(),
+	}
+}
+
+// withEvent applies the change and records the event in the outbox within one
+// transaction, so the event is stored if and only if the change is.
+func (a *Auth) withEvent(ctx context.Context, eventType string, userID int64, change func(ctx context.Context) error) error {
+	return a.txManager.WithTransaction(ctx, func(ctx context.Context) error {
+		if err := change(ctx); err != nil {
+			return err
+		}
+
+		return a.outbox.Add(ctx, a.newEvent(ctx, eventType, userID))
+	})
+}
@@ -0,0 +1,36 @@
+package auth
+
+import (
+	"context"
+	"time"
+
+	"github.com/j0n1que/sso-service/internal/lib/metrics"
+	"github.com/j0n1que/sso-service/internal/lib/tracing"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const hashAlgorithm = "bcrypt"
+
+func hashPassword(ctx context.Context, password string) ([]byte, error) {
+	_, span := tracing.Start(ctx, "bcrypt.GenerateFromPassword")
+	defer span.End()
+
+	start := time.Now()
+	defer func() {
+		metrics.PasswordHashDuration.WithLabelValues(hashAlgorithm, "generate").Observe(time.Since(start).Seconds())
+	}()
+
+	return bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+}
+
+func comparePassword(ctx context.Context, hash []byte, password string) error {
+	_, span := tracing.Start(ctx, "bcrypt.CompareHashAndPassword")
+	defer span.End()
+
+	start := time.Now()
+	defer func() {
+		metrics.PasswordHashDuration.WithLabelValues(hashAlgorithm, "compare").Observe(time.Since(start).Seconds())
+	}()
+
+	return bcrypt.CompareHashAndPassword(hash, []byte(password))
+}
@@ -0,0 +1,139 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/j0n1que/sso-service/internal/domain/models"
+	"github.com/j0n1que/sso-service/internal/lib/jwt"
+	"github.com/j0n1que/sso-service/internal/lib/tracing"
+	"github.com/j0n1que/sso-service/internal/storage"
+)
+
+// ValidateToken returns the claims of token if it is well-formed, signed by
+// the service, unexpired and not revoked.
+func (a *Auth) ValidateToken(ctx context.Context, token string) (jwt.Claims, error) {
+	const op = "auth.ValidateToken"
+
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+
+	claims, err := a.tokens.Parse(token)
+	if err != nil {
+		return jwt.Claims{}, fmt.Errorf("%s: %w: %w", op, ErrInvalidToken, err)
+	}
+
+	revoked, err := a.tknProvider.IsTokenRevoked(ctx, claims.ID)
+	if err != nil {
+		return jwt.Claims{}, fmt.Errorf("%s: %w", op, err)
+	}
+	if revoked {
+		return jwt.Claims{}, fmt.Errorf("%s: %w", op, ErrTokenRevoked)
+	}
+
+	return claims, nil
+}
+
+// RevokeToken denies token until it expires. When token is the stored
+// session of its user, the session is deleted as well.
+func (a *Auth) RevokeToken(ctx context.Context, token string) error {
+	const op = "auth.RevokeToken"
+
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+
+	log := tracing.Logger(ctx, a.log).With(
+		slog.String("op", op),
+	)
+
+	log.Info("revoking token")
+
+	claims, err := a.tokens.Parse(token)
+	if err != nil {
+		log.Warn("invalid token", slog.String("error", err.Error()))
+		a.audit(ctx, models.AuditActionRevokeToken, 0, ErrInvalidToken)
+
+		return fmt.Errorf("%s: %w: %w", op, ErrInvalidToken, err)
+	}
+
+	log = log.With(slog.Int64("user_id", claims.UserID))
+
+	if err := a.tknProvider.RevokeToken(ctx, claims.ID, time.Until(claims.ExpiresAt.Time)); err != nil {
+		log.Error("failed to revoke token", slog.String("error", err.Error()))
+		a.audit(ctx, models.AuditActionRevokeToken, claims.UserID, err)
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	stored, err := a.tknProvider.JWT(ctx, claims.UserID)
+	switch {
+	case err == nil && stored == token:
+		if err := a.endRevokedSession(ctx, token, claims); err != nil {
+			log.Error("failed to delete revoked session", slog.String("error", err.Error()))
+			a.audit(ctx, models.AuditActionRevokeToken, claims.UserID, err)
+
+			return fmt.Errorf("%s: %w", op, err)
+		}
+	case err != nil && !errors.Is(err, storage.ErrTokenNotFound):
+		log.Error("failed to get session", slog.String("error", err.Error()))
+	}
+
+	log.Info("token revoked")
+	a.audit(ctx, models.AuditActionRevokeToken, claims.UserID, nil)
+
+	return nil
+}
+
+// endRevokedSession deletes the session of the revoked token and records the
+// logout. The token store is outside the transaction, so a session deleted
+// without its event is saved back for the revocation to be retried.
+func (a *Auth) endRevokedSession(ctx context.Context, token string, claims jwt.Claims) error {
+	deleted := false
+	err := a.withEvent(ctx, models.EventUserLoggedOut, claims.UserID, func(ctx context.Context) error {
+		if err := a.tknProvider.DeleteJWT(ctx, claims.UserID); err != nil {
+			return err
+		}
+		deleted = true
+
+		return nil
+	})
+	if err != nil && deleted {
+		if err := a.tknProvider.SaveJWT(ctx, token, claims.UserID, time.Until(claims.ExpiresAt.Time)); err != nil {
+			a.log.Error("failed to restore session", slog.String("error", err.Error()))
+		}
+	}
+
+	return err
+}
+
+// revokeStored revokes the stored session token of the user, if any. Tokens
+// issued before they carried an ID cannot be revoked and are skipped.
+func (a *Auth) revokeStored(ctx context.Context, userID int64) error {
+	token, err := a.tknProvider.JWT(ctx, userID)
+	if err != nil {
+		if errors.Is(err, storage.ErrTokenNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	claims, err := a.tokens.Parse(token)
+	if err != nil {
+		return nil
+	}
+
+	return a.tknProvider.RevokeToken(ctx, claims.ID, time.Until(claims.ExpiresAt.Time))
+}
+
+// endSession revokes and deletes the stored session of the user, so that its
+// token is refused for bearer authentication too.
+func (a *Auth) endSession(ctx context.Context, userID int64) error {
+	if err := a.revokeStored(ctx, userID); err != nil {
+		return err
+	}
+
+	return a.tknProvider.DeleteJWT(ctx, userID)
+}
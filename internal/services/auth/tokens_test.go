@@ -0,0 +1,226 @@
+package auth_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/j0n1que/sso-service/internal/config"
+	"github.com/j0n1que/sso-service/internal/domain/models"
+	"github.com/j0n1que/sso-service/internal/lib/jwt"
+	"github.com/j0n1que/sso-service/internal/services/auth"
+	"github.com/j0n1que/sso-service/internal/storage/memory"
+)
+
+func newAuth(t *testing.T) *auth.Auth {
+	t.Helper()
+
+	a, _ := newAuthWithOutbox(t)
+
+	return a
+}
+
+func newAuthWithOutbox(t *testing.T) (*auth.Auth, *memory.OutboxDAO) {
+	t.Helper()
+
+	outbox := memory.NewOutboxDAO()
+
+	return newAuthWith(t, outbox), outbox
+}
+
+func newAuthWith(t *testing.T, outbox auth.EventOutbox) *auth.Auth {
+	t.Helper()
+
+	tokens, err := jwt.New(config.JWTConfig{
+		Secret:    "0123456789abcdef0123456789abcdef",
+		Issuer:    "sso",
+		Audiences: []string{"sso"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	users := memory.New()
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	return auth.New(log, tokens, users, users, memory.NewTokenStorage(), memory.NewAuditDAO(true),
+		outbox, memory.NewTransactor(), time.Hour, time.Hour)
+}
+
+// failingOutbox refuses new events while fail is set.
+type failingOutbox struct {
+	*memory.OutboxDAO
+	fail bool
+}
+
+func (o *failingOutbox) Add(ctx context.Context, events ...models.Event) error {
+	if o.fail {
+		return errors.New("outbox is down")
+	}
+
+	return o.OutboxDAO.Add(ctx, events...)
+}
+
+// eventTypes returns the types of the pending events in the outbox.
+func eventTypes(t *testing.T, outbox *memory.OutboxDAO) []string {
+	t.Helper()
+
+	events, err := outbox.Pending(context.Background(), 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	types := make([]string, 0, len(events))
+	for _, event := range events {
+		types = append(types, event.Type)
+	}
+
+	return types
+}
+
+// login registers a user, logs it in and returns its token and ID.
+func login(t *testing.T, a *auth.Auth) (string, int64) {
+	t.Helper()
+
+	ctx := context.Background()
+
+	if err := a.RegisterUser(ctx, "bob", "hunter2hunter2", "bob_tg"); err != nil {
+		t.Fatal(err)
+	}
+
+	token, err := a.AuthorizeUser(ctx, "bob", "hunter2hunter2")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	claims, err := a.ValidateToken(ctx, token)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return token, claims.UserID
+}
+
+func TestEndingUserRevokesToken(t *testing.T) {
+	tests := []struct {
+		name string
+		end  func(ctx context.Context, a *auth.Auth, userID int64) error
+	}{
+		{
+			name: "disable",
+			end: func(ctx context.Context, a *auth.Auth, userID int64) error {
+				return a.DisableUser(ctx, userID)
+			},
+		},
+		{
+			name: "delete",
+			end: func(ctx context.Context, a *auth.Auth, userID int64) error {
+				return a.DeleteUser(ctx, userID)
+			},
+		},
+		{
+			name: "erase",
+			end: func(ctx context.Context, a *auth.Auth, userID int64) error {
+				_, err := a.EraseUserData(ctx, userID, false)
+				return err
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			a := newAuth(t)
+			token, userID := login(t, a)
+
+			if err := tt.end(ctx, a, userID); err != nil {
+				t.Fatal(err)
+			}
+
+			if _, err := a.ValidateToken(ctx, token); !errors.Is(err, auth.ErrTokenRevoked) {
+				t.Errorf("ValidateToken() error = %v, want %v", err, auth.ErrTokenRevoked)
+			}
+		})
+	}
+}
+
+func TestEraseDryRunKeepsToken(t *testing.T) {
+	ctx := context.Background()
+	a := newAuth(t)
+	token, userID := login(t, a)
+
+	if _, err := a.EraseUserData(ctx, userID, true); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := a.ValidateToken(ctx, token); err != nil {
+		t.Errorf("ValidateToken() error = %v, want nil", err)
+	}
+}
+
+func TestSessionEventsSurviveOutboxFailure(t *testing.T) {
+	ctx := context.Background()
+	outbox := &failingOutbox{OutboxDAO: memory.NewOutboxDAO()}
+	a := newAuthWith(t, outbox)
+
+	if err := a.RegisterUser(ctx, "bob", "hunter2hunter2", "bob_tg"); err != nil {
+		t.Fatal(err)
+	}
+
+	outbox.fail = true
+	if _, err := a.AuthorizeUser(ctx, "bob", "hunter2hunter2"); err == nil {
+		t.Fatal("AuthorizeUser() error = nil with the outbox down")
+	}
+
+	outbox.fail = false
+	token, err := a.AuthorizeUser(ctx, "bob", "hunter2hunter2")
+	if err != nil {
+		t.Fatalf("AuthorizeUser() retry error = %v", err)
+	}
+
+	claims, err := a.ValidateToken(ctx, token)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	outbox.fail = true
+	if err := a.DeleteJWT(ctx, claims.UserID); err == nil {
+		t.Fatal("DeleteJWT() error = nil with the outbox down")
+	}
+
+	outbox.fail = false
+	if err := a.DeleteJWT(ctx, claims.UserID); err != nil {
+		t.Fatalf("DeleteJWT() retry error = %v", err)
+	}
+
+	want := []string{models.EventUserRegistered, models.EventUserLoggedIn, models.EventUserLoggedOut}
+	if got := eventTypes(t, outbox.OutboxDAO); !slices.Equal(got, want) {
+		t.Errorf("events = %v, want %v", got, want)
+	}
+}
+
+func TestRevokeTokenSurvivesOutboxFailure(t *testing.T) {
+	ctx := context.Background()
+	outbox := &failingOutbox{OutboxDAO: memory.NewOutboxDAO()}
+	a := newAuthWith(t, outbox)
+	token, _ := login(t, a)
+
+	outbox.fail = true
+	if err := a.RevokeToken(ctx, token); err == nil {
+		t.Fatal("RevokeToken() error = nil with the outbox down")
+	}
+
+	outbox.fail = false
+	if err := a.RevokeToken(ctx, token); err != nil {
+		t.Fatalf("RevokeToken() retry error = %v", err)
+	}
+
+	want := []string{models.EventUserRegistered, models.EventUserLoggedIn, models.EventUserLoggedOut}
+	if got := eventTypes(t, outbox.OutboxDAO); !slices.Equal(got, want) {
+		t.Errorf("events = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,212 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/j0n1que/sso-service/internal/domain/models"
+	"github.com/j0n1que/sso-service/internal/lib/tracing"
+	"github.com/j0n1que/sso-service/internal/storage"
+)
+
+const (
+	erasedProfile   = "user profile"
+	erasedSession   = "session token"
+	erasedOutbox    = "logins in stored events"
+	erasedPublished = "logins in webhook deliveries and the event stream, once user.erased is published"
+	keptAudit       = "kept: IP addresses and user agents in the audit log, which its hash chain covers"
+)
+
+// ExportUserData collects everything stored about the user into a JSON bundle.
+func (a *Auth) ExportUserData(ctx context.Context, userID int64) ([]byte, error) {
+	const op = "auth.ExportUserData"
+
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+
+	log := tracing.Logger(ctx, a.log).With(
+		slog.String("op", op),
+		slog.Int64("user_id", userID),
+	)
+
+	log.Info("exporting user data")
+
+	user, err := a.usrProvider.UserByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			log.Warn("user not found", slog.String("error", err.Error()))
+
+			return nil, fmt.Errorf("%s: %w", op, ErrUserNotFound)
+		}
+		log.Error("failed to get user", slog.String("error", err.Error()))
+
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	now := time.Now().UTC()
+
+	export := models.UserDataExport{
+		ExportedAt: now,
+		Profile: models.ProfileExport{
+			ID:            user.ID,
+			Login:         user.Login,
+			TelegramLogin: user.TelegramLogin,
+			IsAdmin:       user.IsAdmin,
+			Status:        user.Status,
+			HasPassword:   len(user.PassHash) > 0,
+		},
+		Sessions: []models.SessionExport{},
+	}
+	if !user.DeletedAt.IsZero() {
+		export.Profile.DeletedAt = &user.DeletedAt
+	}
+	if !user.UpdatedAt.IsZero() {
+		export.Profile.UpdatedAt = &user.UpdatedAt
+	}
+
+	session, err := a.session(ctx, userID, now)
+	if err != nil {
+		log.Error("failed to get user's session", slog.String("error", err.Error()))
+
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	if session != nil {
+		export.Sessions = append(export.Sessions, *session)
+	}
+
+	entries, err := a.auditLog.Query(ctx, models.AuditFilter{UserID: userID})
+	if err != nil {
+		log.Error("failed to get user's audit entries", slog.String("error", err.Error()))
+
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	export.AuditEntries = append([]models.AuditEntry{}, entries...)
+
+	data, err := json.Marshal(export)
+	if err != nil {
+		log.Error("failed to encode user data", slog.String("error", err.Error()))
+
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("user data exported")
+	a.audit(ctx, models.AuditActionExportUserData, userID, nil)
+
+	return data, nil
+}
+
+// EraseUserData removes the user from both storages, revokes its session and
+// clears its logins from stored events, and returns what was erased. Copies
+// held by the publishers are cleared when they get the user.erased event. With
+// dryRun set nothing is changed and the returned list shows what would be
+// erased.
+//
+// Audit entries are kept untouched, as the list says, since changing them
+// would break the hash chain. Besides the numeric ID, which is meaningless
+// once the profile is gone, they hold the IP address and user agent of calls.
+func (a *Auth) EraseUserData(ctx context.Context, userID int64, dryRun bool) ([]string, error) {
+	const op = "auth.EraseUserData"
+
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+
+	log := tracing.Logger(ctx, a.log).With(
+		slog.String("op", op),
+		slog.Int64("user_id", userID),
+		slog.Bool("dry_run", dryRun),
+	)
+
+	log.Info("erasing user data")
+
+	if _, err := a.usrProvider.UserByID(ctx, userID); err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			log.Warn("user not found", slog.String("error", err.Error()))
+
+			return nil, fmt.Errorf("%s: %w", op, ErrUserNotFound)
+		}
+		log.Error("failed to get user", slog.String("error", err.Error()))
+
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	erased := []string{erasedProfile}
+
+	session, err := a.session(ctx, userID, time.Now())
+	if err != nil {
+		log.Error("failed to get user's session", slog.String("error", err.Error()))
+
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	if session != nil {
+		erased = append(erased, erasedSession)
+	}
+
+	erased = append(erased, erasedOutbox, erasedPublished, keptAudit)
+
+	if dryRun {
+		log.Info("dry run, nothing erased", slog.Any("erased", erased))
+
+		return erased, nil
+	}
+
+	if err := a.endSession(ctx, userID); err != nil {
+		log.Error("failed to delete token", slog.String("error", err.Error()))
+		a.audit(ctx, models.AuditActionEraseUserData, userID, err)
+
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	err = a.withEvent(ctx, models.EventUserErased, userID, func(ctx context.Context) error {
+		if err := a.usrChanger.EraseUser(ctx, userID); err != nil {
+			return err
+		}
+
+		return a.outbox.EraseUser(ctx, userID)
+	})
+	if err != nil {
+		log.Error("failed to erase user", slog.String("error", err.Error()))
+		a.audit(ctx, models.AuditActionEraseUserData, userID, err)
+
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("user data erased", slog.Any("erased", erased))
+	a.audit(ctx, models.AuditActionEraseUserData, userID, nil)
+
+	return erased, nil
+}
+
+// session returns the user's active session or nil if there is none.
+func (a *Auth) session(ctx context.Context, userID int64, now time.Time) (*models.SessionExport, error) {
+	token, err := a.tknProvider.JWT(ctx, userID)
+	if err != nil {
+		if errors.Is(err, storage.ErrTokenNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	session := &models.SessionExport{}
+
+	// tokens issued before they carried an ID are exported without one
+	if claims, err := a.tokens.Parse(token); err == nil {
+		session.TokenID = claims.ID
+	}
+
+	ttl, err := a.tknProvider.JWTTTL(ctx, userID)
+	if err != nil {
+		if errors.Is(err, storage.ErrTokenNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if ttl > 0 {
+		session.ExpiresAt = now.Add(ttl)
+	}
+
+	return session, nil
+}
@@ -0,0 +1,58 @@
+package auth_test
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestExportLeavesOutToken(t *testing.T) {
+	a := newAuth(t)
+	token, userID := login(t, a)
+
+	data, err := a.ExportUserData(context.Background(), userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if bytes.Contains(data, []byte(token)) {
+		t.Errorf("export contains the session token: %s", data)
+	}
+	if !bytes.Contains(data, []byte(`"token_id":"`)) {
+		t.Errorf("export has no session token ID: %s", data)
+	}
+}
+
+func TestEraseClearsStoredEvents(t *testing.T) {
+	ctx := context.Background()
+	a, outbox := newAuthWithOutbox(t)
+	_, userID := login(t, a)
+
+	erased, err := a.EraseUserData(ctx, userID, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	events, err := outbox.Pending(ctx, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) == 0 {
+		t.Fatal("no events stored")
+	}
+
+	for _, event := range events {
+		if event.Login != "" || event.TelegramLogin != "" {
+			t.Errorf("event %s still has logins %q and %q", event.Type, event.Login, event.TelegramLogin)
+		}
+	}
+
+	var kept bool
+	for _, item := range erased {
+		kept = kept || strings.HasPrefix(item, "kept: ")
+	}
+	if !kept {
+		t.Errorf("erased = %q, want the kept audit data listed", erased)
+	}
+}
@@ -0,0 +1,235 @@
+package webhooks
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/j0n1que/sso-service/internal/domain/models"
+	"github.com/j0n1que/sso-service/internal/lib/caller"
+	"github.com/j0n1que/sso-service/internal/storage"
+)
+
+const secretSize = 32
+
+type Webhooks struct {
+	log   *slog.Logger
+	store WebhookStore
+}
+
+type WebhookStore interface {
+	SaveWebhook(ctx context.Context, webhook models.Webhook) error
+	DeleteWebhook(ctx context.Context, webhookID string) error
+	Webhooks(ctx context.Context) ([]models.Webhook, error)
+	EnqueueDeliveries(ctx context.Context, deliveries ...models.WebhookDelivery) error
+	DeadDeliveries(ctx context.Context, limit int64) ([]models.WebhookDelivery, error)
+	ReplayDelivery(ctx context.Context, deliveryID string) error
+	EraseUserDeliveries(ctx context.Context, userID int64) error
+}
+
+var (
+	ErrInvalidURL       = errors.New("invalid webhook url")
+	ErrUnknownEvent     = errors.New("unknown event type")
+	ErrWebhookNotFound  = errors.New("webhook not found")
+	ErrDeliveryNotFound = errors.New("webhook delivery not found")
+)
+
+func New(log *slog.Logger, store WebhookStore) *Webhooks {
+	return &Webhooks{
+		log:   log,
+		store: store,
+	}
+}
+
+// RegisterWebhook stores a new endpoint and returns it with the generated
+// signing secret. The secret is not returned by any other call.
+func (w *Webhooks) RegisterWebhook(ctx context.Context, endpoint string, eventTypes []string) (models.Webhook, error) {
+	const op = "webhooks.RegisterWebhook"
+
+	log := w.log.With(
+		slog.String("op", op),
+		slog.String("url", endpoint),
+	)
+
+	log.Info("registering webhook")
+
+	u, err := url.Parse(endpoint)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		log.Warn("invalid webhook url")
+
+		return models.Webhook{}, fmt.Errorf("%s: %w", op, ErrInvalidURL)
+	}
+
+	for _, eventType := range eventTypes {
+		if !slices.Contains(models.EventTypes, eventType) {
+			log.Warn("unknown event type", slog.String("event_type", eventType))
+
+			return models.Webhook{}, fmt.Errorf("%s: %w: %s", op, ErrUnknownEvent, eventType)
+		}
+	}
+
+	secret := make([]byte, secretSize)
+	if _, err := rand.Read(secret); err != nil {
+		log.Error("failed to generate secret", slog.String("error", err.Error()))
+
+		return models.Webhook{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	webhook := models.Webhook{
+		ID:         uuid.NewString(),
+		URL:        u.String(),
+		Secret:     hex.EncodeToString(secret),
+		EventTypes: eventTypes,
+		CreatedBy:  caller.FromContext(ctx).UserID,
+		CreatedAt:  time.Now().UTC(),
+	}
+
+	if err := w.store.SaveWebhook(ctx, webhook); err != nil {
+		log.Error("failed to save webhook", slog.String("error", err.Error()))
+
+		return models.Webhook{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("webhook registered", slog.String("webhook_id", webhook.ID))
+
+	return webhook, nil
+}
+
+func (w *Webhooks) DeleteWebhook(ctx context.Context, webhookID string) error {
+	const op = "webhooks.DeleteWebhook"
+
+	log := w.log.With(
+		slog.String("op", op),
+		slog.String("webhook_id", webhookID),
+	)
+
+	log.Info("deleting webhook")
+
+	if err := w.store.DeleteWebhook(ctx, webhookID); err != nil {
+		if errors.Is(err, storage.ErrWebhookNotFound) {
+			log.Warn("webhook not found", slog.String("error", err.Error()))
+
+			return fmt.Errorf("%s: %w", op, ErrWebhookNotFound)
+		}
+		log.Error("failed to delete webhook", slog.String("error", err.Error()))
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("webhook deleted")
+
+	return nil
+}
+
+func (w *Webhooks) ListWebhooks(ctx context.Context) ([]models.Webhook, error) {
+	const op = "webhooks.ListWebhooks"
+
+	log := w.log.With(
+		slog.String("op", op),
+	)
+
+	webhooks, err := w.store.Webhooks(ctx)
+	if err != nil {
+		log.Error("failed to list webhooks", slog.String("error", err.Error()))
+
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return webhooks, nil
+}
+
+func (w *Webhooks) ListDeadDeliveries(ctx context.Context, limit int64) ([]models.WebhookDelivery, error) {
+	const op = "webhooks.ListDeadDeliveries"
+
+	log := w.log.With(
+		slog.String("op", op),
+	)
+
+	deliveries, err := w.store.DeadDeliveries(ctx, limit)
+	if err != nil {
+		log.Error("failed to list dead deliveries", slog.String("error", err.Error()))
+
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return deliveries, nil
+}
+
+func (w *Webhooks) ReplayDelivery(ctx context.Context, deliveryID string) error {
+	const op = "webhooks.ReplayDelivery"
+
+	log := w.log.With(
+		slog.String("op", op),
+		slog.String("delivery_id", deliveryID),
+	)
+
+	log.Info("replaying dead delivery")
+
+	if err := w.store.ReplayDelivery(ctx, deliveryID); err != nil {
+		if errors.Is(err, storage.ErrDeliveryNotFound) {
+			log.Warn("dead delivery not found", slog.String("error", err.Error()))
+
+			return fmt.Errorf("%s: %w", op, ErrDeliveryNotFound)
+		}
+		log.Error("failed to replay delivery", slog.String("error", err.Error()))
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("delivery queued again")
+
+	return nil
+}
+
+// Publish queues a delivery of the event for every subscribed webhook. It lets
+// the outbox relay treat webhooks as one more event publisher.
+func (w *Webhooks) Publish(ctx context.Context, event models.Event) error {
+	const op = "webhooks.Publish"
+
+	if event.Type == models.EventUserErased {
+		if err := w.store.EraseUserDeliveries(ctx, event.UserID); err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	webhooks, err := w.store.Webhooks(ctx)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	now := time.Now().UTC()
+
+	var deliveries []models.WebhookDelivery
+	for _, webhook := range webhooks {
+		if !webhook.Subscribed(event.Type) {
+			continue
+		}
+		deliveries = append(deliveries, models.WebhookDelivery{
+			// derived from the event so that republishing doesn't duplicate deliveries
+			ID:            webhook.ID + ":" + event.ID,
+			WebhookID:     webhook.ID,
+			Event:         event,
+			Status:        models.DeliveryStatusPending,
+			NextAttemptAt: now,
+			History:       []models.DeliveryAttempt{},
+			CreatedAt:     now,
+		})
+	}
+
+	if len(deliveries) == 0 {
+		return nil
+	}
+
+	if err := w.store.EnqueueDeliveries(ctx, deliveries...); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
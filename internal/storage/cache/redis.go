@@ -0,0 +1,53 @@
+package cache
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// Redis is a Backend shared by every instance of the service, so that an
+// invalidation made by one of them is seen by all.
+type Redis struct {
+	db        redis.UniversalClient
+	keyPrefix string
+}
+
+func NewRedis(client redis.UniversalClient, keyPrefix string) *Redis {
+	return &Redis{
+		db:        client,
+		keyPrefix: keyPrefix,
+	}
+}
+
+func (c *Redis) Close() {
+	c.db.Close()
+}
+
+func (c *Redis) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	value, err := c.db.Get(ctx, c.keyPrefix+key).Bytes()
+	if err != nil {
+		if err == redis.Nil {
+			return nil, false, nil
+		}
+		return nil, false, err
+	}
+
+	return value, true, nil
+}
+
+func (c *Redis) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	return c.db.Set(ctx, c.keyPrefix+key, value, ttl).Err()
+}
+
+func (c *Redis) Delete(ctx context.Context, keys ...string) error {
+	// keys are deleted one by one since they may live on different cluster slots
+	for _, key := range keys {
+		if err := c.db.Del(ctx, c.keyPrefix+key).Err(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,252 @@
+// Package cache keeps the users looked up on every authenticated call in a
+// read-through cache in front of the user storage.
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/j0n1que/sso-service/internal/domain/models"
+	"github.com/j0n1que/sso-service/internal/lib/metrics"
+	"github.com/j0n1que/sso-service/internal/storage"
+)
+
+type Backend interface {
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	Delete(ctx context.Context, keys ...string) error
+}
+
+type Store interface {
+	SaveUser(ctx context.Context, user models.User) (int64, error)
+	ChangePassword(ctx context.Context, userID int64, newPasswordHash []byte) error
+	MakeAdmin(ctx context.Context, userID int64) error
+	RevokeAdmin(ctx context.Context, userID int64) error
+	DisableUser(ctx context.Context, userID int64) error
+	EnableUser(ctx context.Context, userID int64) error
+	DeleteUser(ctx context.Context, userID int64) error
+	PurgeDeletedUsers(ctx context.Context, before time.Time) (int64, error)
+	EraseUser(ctx context.Context, userID int64) error
+	User(ctx context.Context, login string) (models.User, error)
+	UserByID(ctx context.Context, userID int64) (models.User, error)
+	IsAdmin(ctx context.Context, userID int64) (bool, error)
+	GetAllUsers(ctx context.Context) ([]models.User, error)
+	GetUserByTelegram(ctx context.Context, telegramLogin string) ([]models.User, error)
+}
+
+// Users caches what the auth check looks up on every call: Access,
+// AccessByTelegram and IsAdmin. It drops the entries of a user once a change
+// made through Users commits. Only UserAccess is cached, never credentials.
+// Purged users are served until their entries expire.
+//
+// A nil backend disables caching, so that callers always have
+// AccessByTelegram.
+type Users struct {
+	Store
+	backend Backend
+	ttl     time.Duration
+}
+
+func NewUsers(store Store, backend Backend, ttl time.Duration) *Users {
+	return &Users{
+		Store:   store,
+		backend: backend,
+		ttl:     ttl,
+	}
+}
+
+func telegramKey(telegramLogin string) string {
+	return "access:telegram:" + telegramLogin
+}
+
+func accessKey(userID int64) string {
+	return "access:user:" + strconv.FormatInt(userID, 10)
+}
+
+func adminKey(userID int64) string {
+	return "users:admin:" + strconv.FormatInt(userID, 10)
+}
+
+// AccessByTelegram returns the access of the users with the given telegram
+// login.
+func (c *Users) AccessByTelegram(ctx context.Context, telegramLogin string) ([]models.UserAccess, error) {
+	key := telegramKey(telegramLogin)
+
+	var access []models.UserAccess
+	if c.get(ctx, "users", key, &access) {
+		return access, nil
+	}
+
+	users, err := c.Store.GetUserByTelegram(ctx, telegramLogin)
+	if err != nil {
+		return nil, err
+	}
+
+	access = make([]models.UserAccess, len(users))
+	for i, user := range users {
+		access[i] = user.Access()
+	}
+
+	c.set(ctx, key, access)
+
+	return access, nil
+}
+
+// Access returns the access of the user with the given ID.
+func (c *Users) Access(ctx context.Context, userID int64) (models.UserAccess, error) {
+	key := accessKey(userID)
+
+	var access models.UserAccess
+	if c.get(ctx, "users", key, &access) {
+		return access, nil
+	}
+
+	user, err := c.Store.UserByID(ctx, userID)
+	if err != nil {
+		return models.UserAccess{}, err
+	}
+
+	access = user.Access()
+	c.set(ctx, key, access)
+
+	return access, nil
+}
+
+func (c *Users) IsAdmin(ctx context.Context, userID int64) (bool, error) {
+	key := adminKey(userID)
+
+	var isAdmin bool
+	if c.get(ctx, "admins", key, &isAdmin) {
+		return isAdmin, nil
+	}
+
+	isAdmin, err := c.Store.IsAdmin(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+
+	c.set(ctx, key, isAdmin)
+
+	return isAdmin, nil
+}
+
+func (c *Users) SaveUser(ctx context.Context, user models.User) (int64, error) {
+	id, err := c.Store.SaveUser(ctx, user)
+	if err != nil {
+		return 0, err
+	}
+
+	c.invalidateAfterCommit(ctx, telegramKey(user.TelegramLogin))
+
+	return id, nil
+}
+
+func (c *Users) ChangePassword(ctx context.Context, userID int64, newPasswordHash []byte) error {
+	return c.change(ctx, userID, func() error {
+		return c.Store.ChangePassword(ctx, userID, newPasswordHash)
+	})
+}
+
+func (c *Users) MakeAdmin(ctx context.Context, userID int64) error {
+	return c.change(ctx, userID, func() error {
+		return c.Store.MakeAdmin(ctx, userID)
+	})
+}
+
+func (c *Users) RevokeAdmin(ctx context.Context, userID int64) error {
+	return c.change(ctx, userID, func() error {
+		return c.Store.RevokeAdmin(ctx, userID)
+	})
+}
+
+func (c *Users) DisableUser(ctx context.Context, userID int64) error {
+	return c.change(ctx, userID, func() error {
+		return c.Store.DisableUser(ctx, userID)
+	})
+}
+
+func (c *Users) EnableUser(ctx context.Context, userID int64) error {
+	return c.change(ctx, userID, func() error {
+		return c.Store.EnableUser(ctx, userID)
+	})
+}
+
+func (c *Users) DeleteUser(ctx context.Context, userID int64) error {
+	return c.change(ctx, userID, func() error {
+		return c.Store.DeleteUser(ctx, userID)
+	})
+}
+
+func (c *Users) EraseUser(ctx context.Context, userID int64) error {
+	return c.change(ctx, userID, func() error {
+		return c.Store.EraseUser(ctx, userID)
+	})
+}
+
+// change runs fn and drops the entries of the user once the change commits,
+// so that a concurrent read cannot cache the state the change replaces. The
+// telegram login is looked up first since the user may be gone afterwards.
+func (c *Users) change(ctx context.Context, userID int64, fn func() error) error {
+	if c.backend == nil {
+		return fn()
+	}
+
+	keys := []string{accessKey(userID), adminKey(userID)}
+	if user, err := c.Store.UserByID(ctx, userID); err == nil {
+		keys = append(keys, telegramKey(user.TelegramLogin))
+	}
+
+	if err := fn(); err != nil {
+		return err
+	}
+
+	c.invalidateAfterCommit(ctx, keys...)
+
+	return nil
+}
+
+// get reports whether key was found and decoded into dst. Backend failures
+// count as misses so that the cache never fails a call.
+func (c *Users) get(ctx context.Context, name, key string, dst any) bool {
+	if c.backend == nil {
+		return false
+	}
+
+	value, ok, err := c.backend.Get(ctx, key)
+	if err == nil && ok && json.Unmarshal(value, dst) == nil {
+		metrics.CacheRequests.WithLabelValues(name, "hit").Inc()
+		return true
+	}
+
+	metrics.CacheRequests.WithLabelValues(name, "miss").Inc()
+
+	return false
+}
+
+func (c *Users) set(ctx context.Context, key string, value any) {
+	if c.backend == nil {
+		return
+	}
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		return
+	}
+
+	// a failed write only costs a later miss
+	_ = c.backend.Set(ctx, key, data, c.ttl)
+}
+
+func (c *Users) invalidateAfterCommit(ctx context.Context, keys ...string) {
+	if c.backend == nil {
+		return
+	}
+
+	storage.AfterCommit(ctx, func() {
+		if err := c.backend.Delete(ctx, keys...); err != nil {
+			metrics.CacheInvalidationErrors.Inc()
+		}
+	})
+}
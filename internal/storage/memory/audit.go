@@ -0,0 +1,107 @@
+package memory
+
+import (
+	"context"
+	"sync"
+
+	"github.com/j0n1que/sso-service/internal/domain/models"
+)
+
+// AuditDAO keeps the append-only audit log in process memory, numbered and
+// optionally hash chained like the database backed logs.
+type AuditDAO struct {
+	mu        sync.RWMutex
+	entries   []models.AuditEntry
+	hashChain bool
+}
+
+func NewAuditDAO(hashChain bool) *AuditDAO {
+	return &AuditDAO{
+		hashChain: hashChain,
+	}
+}
+
+func (dao *AuditDAO) Append(ctx context.Context, entry models.AuditEntry) error {
+	dao.mu.Lock()
+	defer dao.mu.Unlock()
+
+	entry.Timestamp = entry.Timestamp.UTC()
+	entry.Seq = int64(len(dao.entries)) + 1
+
+	if dao.hashChain {
+		if len(dao.entries) > 0 {
+			entry.PrevHash = dao.entries[len(dao.entries)-1].Hash
+		}
+		entry.Hash = entry.ChainHash()
+	}
+
+	dao.entries = append(dao.entries, entry)
+
+	return nil
+}
+
+func (dao *AuditDAO) Query(ctx context.Context, filter models.AuditFilter) ([]models.AuditEntry, error) {
+	dao.mu.RLock()
+	defer dao.mu.RUnlock()
+
+	var entries []models.AuditEntry
+
+	for i := len(dao.entries) - 1; i >= 0; i-- {
+		if filter.Limit > 0 && int64(len(entries)) == filter.Limit {
+			break
+		}
+
+		entry := dao.entries[i]
+		if matchAudit(entry, filter) {
+			entries = append(entries, entry)
+		}
+	}
+
+	return entries, nil
+}
+
+// Verify walks the whole log in order and checks sequence continuity and the
+// hash chain of the entries written with one. It returns the number of checked entries and the sequence number
+// of the first broken entry, or zero if the log is intact.
+func (dao *AuditDAO) Verify(ctx context.Context) (int64, int64, error) {
+	dao.mu.RLock()
+	defer dao.mu.RUnlock()
+
+	var (
+		checked int64
+		chain   = models.AuditChain{Chaining: dao.hashChain}
+	)
+
+	for _, entry := range dao.entries {
+		if broken := chain.Check(entry); broken != 0 {
+			return checked, broken, nil
+		}
+
+		checked++
+	}
+
+	return checked, 0, nil
+}
+
+func matchAudit(entry models.AuditEntry, filter models.AuditFilter) bool {
+	switch {
+	case filter.ActorID != 0 && entry.ActorID != filter.ActorID:
+		return false
+	case filter.TargetID != 0 && entry.TargetID != filter.TargetID:
+		return false
+	case filter.UserID != 0 && entry.ActorID != filter.UserID && entry.TargetID != filter.UserID:
+		return false
+	case filter.Action != "" && entry.Action != filter.Action:
+		return false
+	case filter.Outcome != "" && entry.Outcome != filter.Outcome:
+		return false
+	case !filter.Since.IsZero() && entry.Timestamp.Before(filter.Since):
+		return false
+	case !filter.Until.IsZero() && !entry.Timestamp.Before(filter.Until):
+		return false
+	case filter.AfterSeq != 0 && entry.Seq >= filter.AfterSeq:
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,117 @@
+package memory
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/j0n1que/sso-service/internal/domain/models"
+)
+
+func appendEntries(t *testing.T, dao *AuditDAO, n int) {
+	t.Helper()
+
+	for i := 0; i < n; i++ {
+		err := dao.Append(context.Background(), models.AuditEntry{
+			Timestamp: time.Now(),
+			ActorID:   1,
+			Action:    models.AuditActionLogin,
+			Outcome:   models.AuditOutcomeSuccess,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestAuditVerify(t *testing.T) {
+	tests := []struct {
+		name        string
+		hashChain   bool
+		unchained   int
+		chained     int
+		tamper      func(entries []models.AuditEntry)
+		wantChecked int64
+		wantBroken  int64
+	}{
+		{
+			name:        "chained",
+			hashChain:   true,
+			chained:     3,
+			wantChecked: 3,
+		},
+		{
+			name:        "not chained",
+			unchained:   3,
+			wantChecked: 3,
+		},
+		{
+			name:        "chained after unchained entries",
+			hashChain:   true,
+			unchained:   2,
+			chained:     2,
+			wantChecked: 4,
+		},
+		{
+			name:      "blank hash after the first chained entry",
+			hashChain: true,
+			chained:   3,
+			tamper: func(entries []models.AuditEntry) {
+				entries[1].Hash = ""
+			},
+			wantChecked: 1,
+			wantBroken:  2,
+		},
+		{
+			name:      "blank hash of the first chained entry",
+			hashChain: true,
+			chained:   3,
+			tamper: func(entries []models.AuditEntry) {
+				entries[0].Hash = ""
+			},
+			wantChecked: 1,
+			wantBroken:  2,
+		},
+		{
+			name:      "changed entry",
+			hashChain: true,
+			chained:   3,
+			tamper: func(entries []models.AuditEntry) {
+				entries[1].ActorID = 2
+			},
+			wantChecked: 1,
+			wantBroken:  2,
+		},
+		{
+			name:      "missing entry",
+			unchained: 3,
+			tamper: func(entries []models.AuditEntry) {
+				entries[1].Seq = 3
+			},
+			wantChecked: 1,
+			wantBroken:  2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dao := NewAuditDAO(false)
+			appendEntries(t, dao, tt.unchained)
+
+			dao.hashChain = tt.hashChain
+			appendEntries(t, dao, tt.chained)
+
+			if tt.tamper != nil {
+				tt.tamper(dao.entries)
+			}
+
+			checked, broken, err := dao.Verify(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if checked != tt.wantChecked || broken != tt.wantBroken {
+				t.Errorf("Verify() = %d, %d, want %d, %d", checked, broken, tt.wantChecked, tt.wantBroken)
+			}
+		})
+	}
+}
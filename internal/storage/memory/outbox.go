@@ -0,0 +1,116 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/j0n1que/sso-service/internal/domain/models"
+)
+
+// publishedRetention is how long published events stay in the outbox.
+const publishedRetention = 7 * 24 * time.Hour
+
+type outboxRecord struct {
+	event       models.Event
+	publishedAt time.Time
+	attempts    int
+	lastError   string
+}
+
+// OutboxDAO keeps domain events in process memory until they are published.
+type OutboxDAO struct {
+	mu      sync.Mutex
+	records map[string]*outboxRecord
+}
+
+func NewOutboxDAO() *OutboxDAO {
+	return &OutboxDAO{
+		records: make(map[string]*outboxRecord),
+	}
+}
+
+func (dao *OutboxDAO) Add(ctx context.Context, events ...models.Event) error {
+	dao.mu.Lock()
+	defer dao.mu.Unlock()
+
+	for _, event := range events {
+		dao.records[event.ID] = &outboxRecord{event: event}
+	}
+
+	return nil
+}
+
+// Pending returns unpublished events in the order they occurred. Published
+// events past their retention are removed on the way.
+func (dao *OutboxDAO) Pending(ctx context.Context, limit int64) ([]models.Event, error) {
+	dao.mu.Lock()
+	defer dao.mu.Unlock()
+
+	expired := time.Now().UTC().Add(-publishedRetention)
+
+	var events []models.Event
+
+	for id, record := range dao.records {
+		if record.publishedAt.IsZero() {
+			events = append(events, record.event)
+			continue
+		}
+		if record.publishedAt.Before(expired) {
+			delete(dao.records, id)
+		}
+	}
+
+	sort.Slice(events, func(i, j int) bool {
+		if !events[i].OccurredAt.Equal(events[j].OccurredAt) {
+			return events[i].OccurredAt.Before(events[j].OccurredAt)
+		}
+		return events[i].ID < events[j].ID
+	})
+
+	if limit > 0 && int64(len(events)) > limit {
+		events = events[:limit]
+	}
+
+	return events, nil
+}
+
+// EraseUser clears the personal data of the events of the user, published or
+// not.
+func (dao *OutboxDAO) EraseUser(ctx context.Context, userID int64) error {
+	dao.mu.Lock()
+	defer dao.mu.Unlock()
+
+	for _, record := range dao.records {
+		if record.event.UserID == userID {
+			record.event.ErasePersonalData()
+		}
+	}
+
+	return nil
+}
+
+func (dao *OutboxDAO) MarkPublished(ctx context.Context, eventID string) error {
+	dao.mu.Lock()
+	defer dao.mu.Unlock()
+
+	if record, ok := dao.records[eventID]; ok {
+		record.publishedAt = time.Now().UTC()
+		record.attempts++
+	}
+
+	return nil
+}
+
+func (dao *OutboxDAO) MarkFailed(ctx context.Context, eventID string, reason string) error {
+	dao.mu.Lock()
+	defer dao.mu.Unlock()
+
+	if record, ok := dao.records[eventID]; ok {
+		record.lastError = reason
+		record.attempts++
+	}
+
+	return nil
+}
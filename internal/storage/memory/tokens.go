@@ -0,0 +1,183 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/j0n1que/sso-service/internal/storage"
+)
+
+// sweepInterval is how often expired tokens and revocations are dropped from
+// memory. Expired entries are never returned even before they are swept.
+const sweepInterval = time.Minute
+
+type token struct {
+	value     string
+	expiresAt time.Time
+}
+
+// TokenStorage keeps user tokens in process memory with the same expiration
+// semantics as the Redis store.
+type TokenStorage struct {
+	mu      sync.Mutex
+	tokens  map[int64]token
+	revoked map[string]time.Time
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+func NewTokenStorage() *TokenStorage {
+	ts := &TokenStorage{
+		tokens:  make(map[int64]token),
+		revoked: make(map[string]time.Time),
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+
+	go ts.sweep()
+
+	return ts
+}
+
+// Close stops the background sweeper.
+func (ts *TokenStorage) Close() {
+	close(ts.stop)
+	<-ts.done
+}
+
+func (ts *TokenStorage) Ping(ctx context.Context) error {
+	return nil
+}
+
+func (ts *TokenStorage) JWT(ctx context.Context, userID int64) (string, error) {
+	const op = "storage.memory.JWT"
+
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	t, ok := ts.get(userID, time.Now())
+	if !ok {
+		return "", fmt.Errorf("%s: %w", op, storage.ErrTokenNotFound)
+	}
+
+	return t.value, nil
+}
+
+// JWTTTL returns the remaining lifetime of the user's token.
+func (ts *TokenStorage) JWTTTL(ctx context.Context, userID int64) (time.Duration, error) {
+	const op = "storage.memory.JWTTTL"
+
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	now := time.Now()
+
+	t, ok := ts.get(userID, now)
+	if !ok {
+		return 0, fmt.Errorf("%s: %w", op, storage.ErrTokenNotFound)
+	}
+
+	return t.expiresAt.Sub(now), nil
+}
+
+func (ts *TokenStorage) SaveJWT(ctx context.Context, value string, userID int64, ttl time.Duration) error {
+	const op = "storage.memory.SaveJWT"
+
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	now := time.Now()
+
+	if _, ok := ts.get(userID, now); ok {
+		return fmt.Errorf("%s %w", op, storage.ErrTokenExists)
+	}
+
+	ts.tokens[userID] = token{value: value, expiresAt: now.Add(ttl)}
+
+	return nil
+}
+
+func (ts *TokenStorage) DeleteJWT(ctx context.Context, userID int64) error {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	delete(ts.tokens, userID)
+
+	return nil
+}
+
+// RevokeToken denies the token with the given ID for ttl.
+func (ts *TokenStorage) RevokeToken(ctx context.Context, jti string, ttl time.Duration) error {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	ts.revoked[jti] = time.Now().Add(ttl)
+
+	return nil
+}
+
+func (ts *TokenStorage) IsTokenRevoked(ctx context.Context, jti string) (bool, error) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	expiresAt, ok := ts.revoked[jti]
+
+	return ok && time.Now().Before(expiresAt), nil
+}
+
+// CountSessions returns the number of users that have a live token.
+func (ts *TokenStorage) CountSessions(ctx context.Context) (int64, error) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	now := time.Now()
+
+	var count int64
+	for _, t := range ts.tokens {
+		if now.Before(t.expiresAt) {
+			count++
+		}
+	}
+
+	return count, nil
+}
+
+// get returns the user's token unless it has expired. The caller holds mu.
+func (ts *TokenStorage) get(userID int64, now time.Time) (token, bool) {
+	t, ok := ts.tokens[userID]
+	if !ok || !now.Before(t.expiresAt) {
+		return token{}, false
+	}
+
+	return t, true
+}
+
+func (ts *TokenStorage) sweep() {
+	defer close(ts.done)
+
+	ticker := time.NewTicker(sweepInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ts.stop:
+			return
+		case now := <-ticker.C:
+			ts.mu.Lock()
+			for userID, t := range ts.tokens {
+				if !now.Before(t.expiresAt) {
+					delete(ts.tokens, userID)
+				}
+			}
+			for jti, expiresAt := range ts.revoked {
+				if !now.Before(expiresAt) {
+					delete(ts.revoked, jti)
+				}
+			}
+			ts.mu.Unlock()
+		}
+	}
+}
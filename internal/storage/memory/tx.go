@@ -0,0 +1,20 @@
+package memory
+
+import "context"
+
+// Transactor runs functions without a transaction. Every in-memory store
+// applies its changes atomically on its own, but changes to several stores are
+// not rolled back together.
+type Transactor struct{}
+
+func NewTransactor() *Transactor {
+	return &Transactor{}
+}
+
+func (t *Transactor) Supported() bool {
+	return false
+}
+
+func (t *Transactor) WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	return fn(ctx)
+}
@@ -0,0 +1,258 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/j0n1que/sso-service/internal/domain/models"
+	"github.com/j0n1que/sso-service/internal/lib/userid"
+	"github.com/j0n1que/sso-service/internal/storage"
+)
+
+// UserDAO keeps users in process memory. It follows the semantics of the
+// database backed stores and is meant for local development and tests, where
+// losing the users on restart is acceptable.
+type UserDAO struct {
+	mu    sync.RWMutex
+	users map[int64]models.User
+}
+
+func New() *UserDAO {
+	return &UserDAO{
+		users: make(map[int64]models.User),
+	}
+}
+
+func (dao *UserDAO) SaveUser(ctx context.Context, user models.User) (int64, error) {
+	const op = "storage.memory.SaveUser"
+
+	dao.mu.Lock()
+	defer dao.mu.Unlock()
+
+	for _, u := range dao.users {
+		if u.Login == user.Login {
+			return 0, fmt.Errorf("%s: %w", op, storage.ErrUserExists)
+		}
+	}
+
+	user.Version = 1
+	user.UpdatedAt = time.Now().UTC()
+
+	for attempt := 0; attempt < userid.MaxAttempts; attempt++ {
+		user.ID = userid.New()
+
+		if _, taken := dao.users[user.ID]; !taken {
+			dao.users[user.ID] = user
+			return user.ID, nil
+		}
+	}
+
+	return 0, fmt.Errorf("%s: %w", op, storage.ErrIDCollision)
+}
+
+func (dao *UserDAO) ChangePassword(ctx context.Context, userID int64, newPasswordHash []byte) error {
+	const op = "storage.memory.ChangePassword"
+
+	return dao.update(op, userID, func(user *models.User) error {
+		if user.Status == models.UserStatusDeleted {
+			return storage.ErrUserNotFound
+		}
+		user.PassHash = newPasswordHash
+		return nil
+	})
+}
+
+func (dao *UserDAO) MakeAdmin(ctx context.Context, userID int64) error {
+	const op = "storage.memory.MakeAdmin"
+
+	return dao.update(op, userID, func(user *models.User) error {
+		if user.Status == models.UserStatusDeleted {
+			return storage.ErrUserNotFound
+		}
+		user.IsAdmin = true
+		return nil
+	})
+}
+
+func (dao *UserDAO) RevokeAdmin(ctx context.Context, userID int64) error {
+	const op = "storage.memory.RevokeAdmin"
+
+	return dao.update(op, userID, func(user *models.User) error {
+		if user.Status == models.UserStatusDeleted {
+			return storage.ErrUserNotFound
+		}
+		user.IsAdmin = false
+		return nil
+	})
+}
+
+func (dao *UserDAO) DisableUser(ctx context.Context, userID int64) error {
+	const op = "storage.memory.DisableUser"
+
+	return dao.update(op, userID, func(user *models.User) error {
+		if user.Status == models.UserStatusDeleted {
+			return storage.ErrUserNotFound
+		}
+		user.Status = models.UserStatusDisabled
+		return nil
+	})
+}
+
+// EnableUser makes the user active again. It also restores soft deleted users
+// that have not been purged yet.
+func (dao *UserDAO) EnableUser(ctx context.Context, userID int64) error {
+	const op = "storage.memory.EnableUser"
+
+	return dao.update(op, userID, func(user *models.User) error {
+		user.Status = models.UserStatusActive
+		user.DeletedAt = time.Time{}
+		return nil
+	})
+}
+
+// DeleteUser marks the user as deleted. The user is kept until
+// PurgeDeletedUsers removes it after the retention window.
+func (dao *UserDAO) DeleteUser(ctx context.Context, userID int64) error {
+	const op = "storage.memory.DeleteUser"
+
+	return dao.update(op, userID, func(user *models.User) error {
+		if user.Status == models.UserStatusDeleted {
+			return storage.ErrUserNotFound
+		}
+		user.Status = models.UserStatusDeleted
+		user.DeletedAt = time.Now().UTC()
+		return nil
+	})
+}
+
+// PurgeDeletedUsers permanently removes users soft deleted before the given time
+// and returns how many users were removed.
+func (dao *UserDAO) PurgeDeletedUsers(ctx context.Context, before time.Time) (int64, error) {
+	dao.mu.Lock()
+	defer dao.mu.Unlock()
+
+	var purged int64
+
+	for id, user := range dao.users {
+		if user.Status == models.UserStatusDeleted && !user.DeletedAt.After(before) {
+			delete(dao.users, id)
+			purged++
+		}
+	}
+
+	return purged, nil
+}
+
+// EraseUser permanently removes the user regardless of its status.
+func (dao *UserDAO) EraseUser(ctx context.Context, userID int64) error {
+	const op = "storage.memory.EraseUser"
+
+	dao.mu.Lock()
+	defer dao.mu.Unlock()
+
+	if _, ok := dao.users[userID]; !ok {
+		return fmt.Errorf("%s: %w", op, storage.ErrUserNotFound)
+	}
+
+	delete(dao.users, userID)
+
+	return nil
+}
+
+func (dao *UserDAO) User(ctx context.Context, login string) (models.User, error) {
+	const op = "storage.memory.User"
+
+	dao.mu.RLock()
+	defer dao.mu.RUnlock()
+
+	for _, user := range dao.users {
+		if user.Login == login {
+			return user, nil
+		}
+	}
+
+	return models.User{}, fmt.Errorf("%s: %w", op, storage.ErrUserNotFound)
+}
+
+func (dao *UserDAO) UserByID(ctx context.Context, userID int64) (models.User, error) {
+	const op = "storage.memory.UserByID"
+
+	dao.mu.RLock()
+	defer dao.mu.RUnlock()
+
+	user, ok := dao.users[userID]
+	if !ok {
+		return models.User{}, fmt.Errorf("%s: %w", op, storage.ErrUserNotFound)
+	}
+
+	return user, nil
+}
+
+func (dao *UserDAO) IsAdmin(ctx context.Context, userID int64) (bool, error) {
+	const op = "storage.memory.IsAdmin"
+
+	dao.mu.RLock()
+	defer dao.mu.RUnlock()
+
+	user, ok := dao.users[userID]
+	if !ok {
+		return false, fmt.Errorf("%s: %w", op, storage.ErrUserNotFound)
+	}
+
+	return user.IsAdmin, nil
+}
+
+func (dao *UserDAO) GetUserByTelegram(ctx context.Context, telegramLogin string) ([]models.User, error) {
+	return dao.find(func(user models.User) bool {
+		return user.TelegramLogin == telegramLogin && user.Status != models.UserStatusDeleted
+	}), nil
+}
+
+func (dao *UserDAO) GetAllUsers(ctx context.Context) ([]models.User, error) {
+	return dao.find(func(user models.User) bool {
+		return user.Status != models.UserStatusDeleted
+	}), nil
+}
+
+// update applies fn to the stored user under the write lock. Errors returned
+// by fn are wrapped with op.
+func (dao *UserDAO) update(op string, userID int64, fn func(user *models.User) error) error {
+	dao.mu.Lock()
+	defer dao.mu.Unlock()
+
+	user, ok := dao.users[userID]
+	if !ok {
+		return fmt.Errorf("%s: %w", op, storage.ErrUserNotFound)
+	}
+
+	if err := fn(&user); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	user.Version++
+	user.UpdatedAt = time.Now().UTC()
+	dao.users[userID] = user
+
+	return nil
+}
+
+// find returns the users matching the predicate ordered by ID.
+func (dao *UserDAO) find(match func(user models.User) bool) []models.User {
+	dao.mu.RLock()
+	defer dao.mu.RUnlock()
+
+	var users []models.User
+
+	for _, user := range dao.users {
+		if match(user) {
+			users = append(users, user)
+		}
+	}
+
+	sort.Slice(users, func(i, j int) bool { return users[i].ID < users[j].ID })
+
+	return users
+}
@@ -0,0 +1,218 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/j0n1que/sso-service/internal/domain/models"
+	"github.com/j0n1que/sso-service/internal/storage"
+)
+
+// deliveredRetention is how long successful deliveries are kept for inspection.
+const deliveredRetention = 7 * 24 * time.Hour
+
+// WebhookDAO keeps webhooks and their delivery queue in process memory.
+type WebhookDAO struct {
+	mu         sync.Mutex
+	webhooks   map[string]models.Webhook
+	deliveries map[string]models.WebhookDelivery
+}
+
+func NewWebhookDAO() *WebhookDAO {
+	return &WebhookDAO{
+		webhooks:   make(map[string]models.Webhook),
+		deliveries: make(map[string]models.WebhookDelivery),
+	}
+}
+
+func (dao *WebhookDAO) SaveWebhook(ctx context.Context, webhook models.Webhook) error {
+	dao.mu.Lock()
+	defer dao.mu.Unlock()
+
+	dao.webhooks[webhook.ID] = webhook
+
+	return nil
+}
+
+func (dao *WebhookDAO) DeleteWebhook(ctx context.Context, webhookID string) error {
+	const op = "storage.memory.DeleteWebhook"
+
+	dao.mu.Lock()
+	defer dao.mu.Unlock()
+
+	if _, ok := dao.webhooks[webhookID]; !ok {
+		return fmt.Errorf("%s: %w", op, storage.ErrWebhookNotFound)
+	}
+
+	delete(dao.webhooks, webhookID)
+
+	return nil
+}
+
+func (dao *WebhookDAO) Webhook(ctx context.Context, webhookID string) (models.Webhook, error) {
+	const op = "storage.memory.Webhook"
+
+	dao.mu.Lock()
+	defer dao.mu.Unlock()
+
+	webhook, ok := dao.webhooks[webhookID]
+	if !ok {
+		return models.Webhook{}, fmt.Errorf("%s: %w", op, storage.ErrWebhookNotFound)
+	}
+
+	return webhook, nil
+}
+
+func (dao *WebhookDAO) Webhooks(ctx context.Context) ([]models.Webhook, error) {
+	dao.mu.Lock()
+	defer dao.mu.Unlock()
+
+	var webhooks []models.Webhook
+	for _, webhook := range dao.webhooks {
+		webhooks = append(webhooks, webhook)
+	}
+
+	sort.Slice(webhooks, func(i, j int) bool { return webhooks[i].CreatedAt.Before(webhooks[j].CreatedAt) })
+
+	return webhooks, nil
+}
+
+// EnqueueDeliveries stores new deliveries. Deliveries that are already queued
+// are skipped, so enqueueing the same event twice is harmless. Delivered
+// deliveries past their retention are removed on the way.
+func (dao *WebhookDAO) EnqueueDeliveries(ctx context.Context, deliveries ...models.WebhookDelivery) error {
+	dao.mu.Lock()
+	defer dao.mu.Unlock()
+
+	expired := time.Now().UTC().Add(-deliveredRetention)
+	for id, delivery := range dao.deliveries {
+		if delivery.Status == models.DeliveryStatusDelivered && delivery.CreatedAt.Before(expired) {
+			delete(dao.deliveries, id)
+		}
+	}
+
+	for _, delivery := range deliveries {
+		if _, ok := dao.deliveries[delivery.ID]; !ok {
+			dao.deliveries[delivery.ID] = delivery
+		}
+	}
+
+	return nil
+}
+
+// ClaimDueDelivery picks a pending delivery whose next attempt is due and
+// postpones it by lease so that other workers skip it while it is in flight.
+// It returns storage.ErrDeliveryNotFound when nothing is due.
+func (dao *WebhookDAO) ClaimDueDelivery(ctx context.Context, now time.Time, lease time.Duration) (models.WebhookDelivery, error) {
+	const op = "storage.memory.ClaimDueDelivery"
+
+	dao.mu.Lock()
+	defer dao.mu.Unlock()
+
+	var (
+		due   models.WebhookDelivery
+		found bool
+	)
+
+	for _, delivery := range dao.deliveries {
+		if delivery.Status != models.DeliveryStatusPending || delivery.NextAttemptAt.After(now) {
+			continue
+		}
+		if !found || delivery.NextAttemptAt.Before(due.NextAttemptAt) {
+			due, found = delivery, true
+		}
+	}
+
+	if !found {
+		return models.WebhookDelivery{}, fmt.Errorf("%s: %w", op, storage.ErrDeliveryNotFound)
+	}
+
+	claimed := due
+	claimed.NextAttemptAt = now.Add(lease)
+	dao.deliveries[due.ID] = claimed
+
+	return due, nil
+}
+
+// RecordAttempt stores the outcome of a delivery attempt together with the new
+// status and the time of the next attempt.
+func (dao *WebhookDAO) RecordAttempt(ctx context.Context, deliveryID string, attempt models.DeliveryAttempt, status string, nextAttemptAt time.Time) error {
+	dao.mu.Lock()
+	defer dao.mu.Unlock()
+
+	delivery, ok := dao.deliveries[deliveryID]
+	if !ok {
+		return nil
+	}
+
+	delivery.Status = status
+	delivery.NextAttemptAt = nextAttemptAt
+	delivery.LastError = attempt.Error
+	delivery.Attempts++
+	delivery.History = append(append([]models.DeliveryAttempt(nil), delivery.History...), attempt)
+
+	dao.deliveries[deliveryID] = delivery
+
+	return nil
+}
+
+func (dao *WebhookDAO) DeadDeliveries(ctx context.Context, limit int64) ([]models.WebhookDelivery, error) {
+	dao.mu.Lock()
+	defer dao.mu.Unlock()
+
+	var deliveries []models.WebhookDelivery
+	for _, delivery := range dao.deliveries {
+		if delivery.Status == models.DeliveryStatusDead {
+			deliveries = append(deliveries, delivery)
+		}
+	}
+
+	sort.Slice(deliveries, func(i, j int) bool { return deliveries[i].CreatedAt.After(deliveries[j].CreatedAt) })
+
+	if limit > 0 && int64(len(deliveries)) > limit {
+		deliveries = deliveries[:limit]
+	}
+
+	return deliveries, nil
+}
+
+// ReplayDelivery moves a dead delivery back to the queue with a fresh attempt
+// budget. The attempt history is kept.
+func (dao *WebhookDAO) ReplayDelivery(ctx context.Context, deliveryID string) error {
+	const op = "storage.memory.ReplayDelivery"
+
+	dao.mu.Lock()
+	defer dao.mu.Unlock()
+
+	delivery, ok := dao.deliveries[deliveryID]
+	if !ok || delivery.Status != models.DeliveryStatusDead {
+		return fmt.Errorf("%s: %w", op, storage.ErrDeliveryNotFound)
+	}
+
+	delivery.Status = models.DeliveryStatusPending
+	delivery.Attempts = 0
+	delivery.NextAttemptAt = time.Now().UTC()
+
+	dao.deliveries[deliveryID] = delivery
+
+	return nil
+}
+
+// EraseUserDeliveries clears the personal data of the events of the user in
+// every delivery, whatever its status.
+func (dao *WebhookDAO) EraseUserDeliveries(ctx context.Context, userID int64) error {
+	dao.mu.Lock()
+	defer dao.mu.Unlock()
+
+	for id, delivery := range dao.deliveries {
+		if delivery.Event.UserID == userID {
+			delivery.Event.ErasePersonalData()
+			dao.deliveries[id] = delivery
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,191 @@
+package mongo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/j0n1que/sso-service/internal/domain/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// maxAppendAttempts bounds retries when concurrent writers race for the same
+// sequence number.
+const maxAppendAttempts = 5
+
+// AuditDAO stores the append-only audit log. Entries get consecutive sequence
+// numbers and, with hash chaining enabled, each entry carries the hash of its
+// predecessor so that modified or removed entries can be detected.
+type AuditDAO struct {
+	c         *mongo.Collection
+	hashChain bool
+}
+
+func NewAuditDAO(ctx context.Context, db Database, hashChain bool) *AuditDAO {
+	return &AuditDAO{
+		c:         db.Collection("audit_log"),
+		hashChain: hashChain,
+	}
+}
+
+func (dao *AuditDAO) Append(ctx context.Context, entry models.AuditEntry) error {
+	const op = "storage.mongo.AuditAppend"
+
+	// mongo keeps milliseconds only, truncate so the stored entry hashes the same
+	entry.Timestamp = entry.Timestamp.UTC().Truncate(time.Millisecond)
+
+	for attempt := 0; attempt < maxAppendAttempts; attempt++ {
+		last, err := dao.last(ctx)
+		if err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+
+		entry.Seq = last.Seq + 1
+		if dao.hashChain {
+			entry.PrevHash = last.Hash
+			entry.Hash = entry.ChainHash()
+		}
+
+		_, err = dao.c.InsertOne(ctx, entry)
+		if err == nil {
+			return nil
+		}
+		if !mongo.IsDuplicateKeyError(err) {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	return fmt.Errorf("%s: sequence conflict after %d attempts", op, maxAppendAttempts)
+}
+
+func (dao *AuditDAO) Query(ctx context.Context, filter models.AuditFilter) ([]models.AuditEntry, error) {
+	const op = "storage.mongo.AuditQuery"
+
+	query := bson.D{}
+	if filter.ActorID != 0 {
+		query = append(query, bson.E{Key: "actorId", Value: filter.ActorID})
+	}
+	if filter.TargetID != 0 {
+		query = append(query, bson.E{Key: "targetId", Value: filter.TargetID})
+	}
+	if filter.UserID != 0 {
+		query = append(query, bson.E{Key: "$or", Value: bson.A{
+			bson.D{{Key: "actorId", Value: filter.UserID}},
+			bson.D{{Key: "targetId", Value: filter.UserID}},
+		}})
+	}
+	if filter.Action != "" {
+		query = append(query, bson.E{Key: "action", Value: filter.Action})
+	}
+	if filter.Outcome != "" {
+		query = append(query, bson.E{Key: "outcome", Value: filter.Outcome})
+	}
+
+	timestamp := bson.D{}
+	if !filter.Since.IsZero() {
+		timestamp = append(timestamp, bson.E{Key: "$gte", Value: filter.Since})
+	}
+	if !filter.Until.IsZero() {
+		timestamp = append(timestamp, bson.E{Key: "$lt", Value: filter.Until})
+	}
+	if len(timestamp) > 0 {
+		query = append(query, bson.E{Key: "timestamp", Value: timestamp})
+	}
+
+	if filter.AfterSeq != 0 {
+		query = append(query, bson.E{Key: "_id", Value: bson.D{{Key: "$lt", Value: filter.AfterSeq}}})
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "_id", Value: -1}})
+	if filter.Limit > 0 {
+		opts.SetLimit(filter.Limit)
+	}
+
+	cursor, err := dao.c.Find(ctx, query, opts)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	var entries []models.AuditEntry
+	if err := cursor.All(ctx, &entries); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return entries, nil
+}
+
+// Verify walks the whole log in order and checks sequence continuity and the
+// hash chain of the entries written with one. It returns the number of checked entries and the sequence number
+// of the first broken entry, or zero if the log is intact.
+func (dao *AuditDAO) Verify(ctx context.Context) (int64, int64, error) {
+	const op = "storage.mongo.AuditVerify"
+
+	cursor, err := dao.c.Find(ctx, bson.D{}, options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}))
+	if err != nil {
+		return 0, 0, fmt.Errorf("%s: %w", op, err)
+	}
+	defer cursor.Close(ctx)
+
+	var (
+		checked int64
+		chain   = models.AuditChain{Chaining: dao.hashChain}
+	)
+
+	for cursor.Next(ctx) {
+		var entry models.AuditEntry
+		if err := cursor.Decode(&entry); err != nil {
+			return checked, 0, fmt.Errorf("%s: %w", op, err)
+		}
+
+		if broken := chain.Check(entry); broken != 0 {
+			return checked, broken, nil
+		}
+
+		checked++
+	}
+
+	if err := cursor.Err(); err != nil {
+		return checked, 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return checked, 0, nil
+}
+
+func (dao *AuditDAO) EnsureIndexes(ctx context.Context) error {
+	const op = "storage.mongo.AuditEnsureIndexes"
+
+	indexModels := []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "actorId", Value: 1}},
+		},
+		{
+			Keys: bson.D{{Key: "targetId", Value: 1}},
+		},
+		{
+			Keys: bson.D{{Key: "action", Value: 1}, {Key: "timestamp", Value: -1}},
+		},
+	}
+
+	_, err := dao.c.Indexes().CreateMany(ctx, indexModels)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (dao *AuditDAO) last(ctx context.Context) (models.AuditEntry, error) {
+	opts := options.FindOne().SetSort(bson.D{{Key: "_id", Value: -1}})
+
+	var entry models.AuditEntry
+
+	err := dao.c.FindOne(ctx, bson.D{}, opts).Decode(&entry)
+	if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+		return models.AuditEntry{}, err
+	}
+
+	return entry, nil
+}
@@ -0,0 +1,93 @@
+package mongo
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/j0n1que/sso-service/internal/config"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readconcern"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+	"go.mongodb.org/mongo-driver/mongo/writeconcern"
+)
+
+// Database holds the collections of the service. Prefix is prepended to every
+// collection name so that several environments can share one database.
+type Database struct {
+	db     *mongo.Database
+	prefix string
+}
+
+func NewDatabase(client *mongo.Client, name, prefix string) Database {
+	return Database{
+		db:     client.Database(name),
+		prefix: prefix,
+	}
+}
+
+func (d Database) Collection(name string) *mongo.Collection {
+	return d.db.Collection(d.prefix + name)
+}
+
+// Connect opens a client to uri tuned by cfg and pings the server so that an
+// unreachable or misconfigured cluster fails at startup. Settings given in the
+// URI take precedence over cfg.
+func Connect(ctx context.Context, uri string, cfg config.MongoConfig) (*mongo.Client, error) {
+	const op = "storage.mongo.Connect"
+
+	opts := options.Client().
+		SetMinPoolSize(cfg.MinPoolSize).
+		SetMaxPoolSize(cfg.MaxPoolSize).
+		SetMaxConnIdleTime(cfg.MaxConnIdleTime).
+		SetConnectTimeout(cfg.ConnectTimeout).
+		SetServerSelectionTimeout(cfg.ServerSelectionTimeout).
+		SetRetryWrites(cfg.RetryWrites).
+		SetRetryReads(cfg.RetryReads)
+
+	if cfg.ReadConcern != "" {
+		opts.SetReadConcern(&readconcern.ReadConcern{Level: cfg.ReadConcern})
+	}
+
+	if cfg.ReadPreference != "" {
+		mode, err := readpref.ModeFromString(cfg.ReadPreference)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		rp, err := readpref.New(mode)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		opts.SetReadPreference(rp)
+	}
+
+	if cfg.WriteConcern != "" || cfg.Journal {
+		wc := &writeconcern.WriteConcern{WTimeout: cfg.WriteTimeout}
+		if cfg.WriteConcern != "" {
+			wc.W = cfg.WriteConcern
+			if n, err := strconv.Atoi(cfg.WriteConcern); err == nil {
+				wc.W = n
+			}
+		}
+		if cfg.Journal {
+			wc.Journal = &cfg.Journal
+		}
+		opts.SetWriteConcern(wc)
+	}
+
+	// applied last so that the URI overrides the settings above
+	opts.ApplyURI(uri)
+
+	client, err := mongo.Connect(ctx, opts)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := client.Ping(ctx, readpref.Primary()); err != nil {
+		_ = client.Disconnect(ctx)
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return client, nil
+}
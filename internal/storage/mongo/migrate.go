@@ -0,0 +1,248 @@
+package mongo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	// lockID is the _id of the document that guards migrations.
+	lockID = "migrations"
+	// lockLease is how long a lock is held before other replicas may take it
+	// over, in case its owner died while migrating.
+	lockLease = 10 * time.Minute
+	// lockPoll is how often a replica waiting for the lock retries.
+	lockPoll = time.Second
+)
+
+// Migration changes the schema or the data of the database. Migrations run
+// without a transaction, so Up and Down must be safe to run again after a
+// partial failure.
+type Migration struct {
+	Version     int64
+	Description string
+	Up          func(ctx context.Context, db Database) error
+	Down        func(ctx context.Context, db Database) error
+}
+
+type migrationRecord struct {
+	Version     int64     `bson:"_id"`
+	Description string    `bson:"description"`
+	AppliedAt   time.Time `bson:"appliedAt"`
+}
+
+// Migrator applies and rolls back migrations. Applied migrations are recorded
+// in the schema_migrations collection and a lock document makes sure only one
+// replica migrates at a time.
+type Migrator struct {
+	log        *slog.Logger
+	db         Database
+	records    *mongo.Collection
+	locks      *mongo.Collection
+	migrations []Migration
+	owner      string
+}
+
+func NewMigrator(log *slog.Logger, db Database) *Migrator {
+	sorted := append([]Migration(nil), migrations...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Version < sorted[j].Version })
+
+	host, _ := os.Hostname()
+
+	return &Migrator{
+		log:        log,
+		db:         db,
+		records:    db.Collection("schema_migrations"),
+		locks:      db.Collection("schema_migrations_lock"),
+		migrations: sorted,
+		owner:      fmt.Sprintf("%s/%d/%s", host, os.Getpid(), uuid.NewString()),
+	}
+}
+
+// Up applies all pending migrations in version order and returns them. With
+// dryRun the pending migrations are only returned.
+func (m *Migrator) Up(ctx context.Context, dryRun bool) ([]Migration, error) {
+	const op = "storage.mongo.MigrateUp"
+
+	log := m.log.With(slog.String("op", op), slog.Bool("dry_run", dryRun))
+
+	if !dryRun {
+		release, err := m.lock(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		defer release()
+	}
+
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	var pending []Migration
+	for _, migration := range m.migrations {
+		if _, ok := applied[migration.Version]; !ok {
+			pending = append(pending, migration)
+		}
+	}
+
+	for _, migration := range pending {
+		log := log.With(slog.Int64("version", migration.Version), slog.String("description", migration.Description))
+
+		if dryRun {
+			log.Info("would apply migration")
+			continue
+		}
+
+		log.Info("applying migration")
+
+		if err := migration.Up(ctx, m.db); err != nil {
+			return nil, fmt.Errorf("%s: migration %d: %w", op, migration.Version, err)
+		}
+
+		record := migrationRecord{
+			Version:     migration.Version,
+			Description: migration.Description,
+			AppliedAt:   time.Now().UTC(),
+		}
+		if _, err := m.records.InsertOne(ctx, record); err != nil {
+			return nil, fmt.Errorf("%s: migration %d: %w", op, migration.Version, err)
+		}
+	}
+
+	return pending, nil
+}
+
+// Down rolls back the given number of most recently applied migrations and
+// returns them. With dryRun the migrations are only returned.
+func (m *Migrator) Down(ctx context.Context, steps int, dryRun bool) ([]Migration, error) {
+	const op = "storage.mongo.MigrateDown"
+
+	log := m.log.With(slog.String("op", op), slog.Bool("dry_run", dryRun))
+
+	if !dryRun {
+		release, err := m.lock(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		defer release()
+	}
+
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	var rollback []Migration
+	for i := len(m.migrations) - 1; i >= 0 && len(rollback) < steps; i-- {
+		if _, ok := applied[m.migrations[i].Version]; ok {
+			rollback = append(rollback, m.migrations[i])
+		}
+	}
+
+	for _, migration := range rollback {
+		log := log.With(slog.Int64("version", migration.Version), slog.String("description", migration.Description))
+
+		if dryRun {
+			log.Info("would roll back migration")
+			continue
+		}
+
+		log.Info("rolling back migration")
+
+		if err := migration.Down(ctx, m.db); err != nil {
+			return nil, fmt.Errorf("%s: migration %d: %w", op, migration.Version, err)
+		}
+
+		if _, err := m.records.DeleteOne(ctx, bson.D{{Key: "_id", Value: migration.Version}}); err != nil {
+			return nil, fmt.Errorf("%s: migration %d: %w", op, migration.Version, err)
+		}
+	}
+
+	return rollback, nil
+}
+
+// applied returns the recorded migrations by version. Versions unknown to this
+// build mean the database was migrated by a newer one and are only logged.
+func (m *Migrator) applied(ctx context.Context) (map[int64]migrationRecord, error) {
+	cursor, err := m.records.Find(ctx, bson.D{})
+	if err != nil {
+		return nil, err
+	}
+
+	var records []migrationRecord
+	if err := cursor.All(ctx, &records); err != nil {
+		return nil, err
+	}
+
+	known := make(map[int64]bool, len(m.migrations))
+	for _, migration := range m.migrations {
+		known[migration.Version] = true
+	}
+
+	applied := make(map[int64]migrationRecord, len(records))
+	for _, record := range records {
+		applied[record.Version] = record
+		if !known[record.Version] {
+			m.log.Warn("database has a migration unknown to this build", slog.Int64("version", record.Version))
+		}
+	}
+
+	return applied, nil
+}
+
+// lock waits until it holds the migrations lock or ctx is done and returns a
+// function releasing it.
+func (m *Migrator) lock(ctx context.Context) (func(), error) {
+	for {
+		now := time.Now().UTC()
+
+		// the filter matches only an expired lock, so a live lock makes the
+		// upsert insert a second document with the same _id, which fails
+		filter := bson.D{
+			{Key: "_id", Value: lockID},
+			{Key: "expiresAt", Value: bson.D{{Key: "$lt", Value: now}}},
+		}
+		update := bson.D{{Key: "$set", Value: bson.D{
+			{Key: "owner", Value: m.owner},
+			{Key: "expiresAt", Value: now.Add(lockLease)},
+		}}}
+
+		_, err := m.locks.UpdateOne(ctx, filter, update, options.Update().SetUpsert(true))
+		if err == nil {
+			return m.unlock, nil
+		}
+		if !mongo.IsDuplicateKeyError(err) {
+			return nil, err
+		}
+
+		m.log.Info("waiting for another replica to finish migrating")
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(lockPoll):
+		}
+	}
+}
+
+func (m *Migrator) unlock() {
+	// the lock outlives the caller's context, it must be released anyway
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	filter := bson.D{{Key: "_id", Value: lockID}, {Key: "owner", Value: m.owner}}
+	if _, err := m.locks.DeleteOne(ctx, filter); err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+		m.log.Error("failed to release migrations lock", slog.String("error", err.Error()))
+	}
+}
@@ -0,0 +1,46 @@
+package mongo
+
+import (
+	"context"
+
+	"github.com/j0n1que/sso-service/internal/domain/models"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// migrations lists every migration. New migrations are appended with the next
+// version; applied migrations must never change.
+var migrations = []Migration{
+	{
+		Version:     1,
+		Description: "set status of users stored before statuses",
+		Up: func(ctx context.Context, db Database) error {
+			_, err := db.Collection("users").UpdateMany(ctx,
+				bson.D{{Key: "status", Value: bson.D{{Key: "$in", Value: bson.A{nil, ""}}}}},
+				bson.D{{Key: "$set", Value: bson.D{{Key: "status", Value: models.UserStatusActive}}}},
+			)
+			return err
+		},
+		// an active status means the same as a missing one, keep it
+		Down: func(ctx context.Context, db Database) error {
+			return nil
+		},
+	},
+	{
+		Version:     2,
+		Description: "set version of users stored before versioning",
+		Up: func(ctx context.Context, db Database) error {
+			_, err := db.Collection("users").UpdateMany(ctx,
+				bson.D{{Key: "version", Value: bson.D{{Key: "$exists", Value: false}}}},
+				bson.D{{Key: "$set", Value: bson.D{{Key: "version", Value: int64(0)}}}},
+			)
+			return err
+		},
+		Down: func(ctx context.Context, db Database) error {
+			_, err := db.Collection("users").UpdateMany(ctx,
+				bson.D{{Key: "version", Value: int64(0)}},
+				bson.D{{Key: "$unset", Value: bson.D{{Key: "version", Value: ""}}}},
+			)
+			return err
+		},
+	},
+}
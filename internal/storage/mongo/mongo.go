@@ -4,9 +4,13 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"slices"
+	"time"
 
-	"github.com/google/uuid"
 	"github.com/j0n1que/sso-service/internal/domain/models"
+	"github.com/j0n1que/sso-service/internal/lib/metrics"
+	"github.com/j0n1que/sso-service/internal/lib/tracing"
+	"github.com/j0n1que/sso-service/internal/lib/userid"
 	"github.com/j0n1que/sso-service/internal/storage"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
@@ -17,83 +21,244 @@ type UserDAO struct {
 	c *mongo.Collection
 }
 
-func New(ctx context.Context, client *mongo.Client) *UserDAO {
+func New(ctx context.Context, db Database) *UserDAO {
 	return &UserDAO{
-		c: client.Database("core").Collection("users"),
+		c: db.Collection("users"),
 	}
 }
 
-func (dao *UserDAO) SaveUser(ctx context.Context, user models.User) error {
+func (dao *UserDAO) SaveUser(ctx context.Context, user models.User) (_ int64, err error) {
 	const op = "storage.mongo.SaveUser"
 
-	user.ID = int64(uuid.New().ID())
-	_, err := dao.c.InsertOne(ctx, user)
+	defer metrics.ObserveStorage("mongo", op, time.Now(), &err)
 
-	if err != nil {
-		if mongo.IsDuplicateKeyError(err) {
-			return fmt.Errorf("%s: %w", op, storage.ErrUserExists)
+	ctx, span := tracing.Start(ctx, op)
+	defer tracing.End(span, &err)
+
+	user.Version = 1
+	user.UpdatedAt = time.Now().UTC()
+
+	for attempt := 0; attempt < userid.MaxAttempts; attempt++ {
+		user.ID = userid.New()
+
+		inserted, err := dao.insertIfFree(ctx, user)
+		if err != nil {
+			if mongo.IsDuplicateKeyError(err) {
+				return 0, fmt.Errorf("%s: %w", op, storage.ErrUserExists)
+			}
+			return 0, fmt.Errorf("%s: %w", op, err)
 		}
+
+		if inserted {
+			return user.ID, nil
+		}
+	}
+
+	return 0, fmt.Errorf("%s: %w", op, storage.ErrIDCollision)
+}
+
+func (dao *UserDAO) ChangePassword(ctx context.Context, userID int64, newPasswordHash []byte) (err error) {
+	const op = "storage.mongo.ChangePassword"
+
+	defer metrics.ObserveStorage("mongo", op, time.Now(), &err)
+
+	ctx, span := tracing.Start(ctx, op)
+	defer tracing.End(span, &err)
+
+	matched, err := dao.set(ctx, notDeleted(userID), bson.D{{Key: "passHash", Value: newPasswordHash}})
+	if err != nil {
 		return fmt.Errorf("%s: %w", op, err)
 	}
 
+	if !matched {
+		return fmt.Errorf("%s: %w", op, storage.ErrUserNotFound)
+	}
 	return nil
 }
 
-func (dao *UserDAO) ChangePassword(ctx context.Context, userID int64, newPasswordHash []byte) error {
-	const op = "storage.mongo.ChangePassword"
+func (dao *UserDAO) MakeAdmin(ctx context.Context, userID int64) (err error) {
+	const op = "storage.mongo.MakeAdmin"
 
-	user, err := dao.findByID(ctx, userID)
+	defer metrics.ObserveStorage("mongo", op, time.Now(), &err)
+
+	ctx, span := tracing.Start(ctx, op)
+	defer tracing.End(span, &err)
+
+	matched, err := dao.set(ctx, notDeleted(userID), bson.D{{Key: "isAdmin", Value: true}})
 	if err != nil {
-		if errors.Is(err, mongo.ErrNoDocuments) {
-			return fmt.Errorf("%s: %w", op, storage.ErrUserNotFound)
-		}
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if !matched {
+		return fmt.Errorf("%s: %w", op, storage.ErrUserNotFound)
+	}
+	return nil
+}
+
+func (dao *UserDAO) RevokeAdmin(ctx context.Context, userID int64) (err error) {
+	const op = "storage.mongo.RevokeAdmin"
+
+	defer metrics.ObserveStorage("mongo", op, time.Now(), &err)
+
+	ctx, span := tracing.Start(ctx, op)
+	defer tracing.End(span, &err)
 
+	matched, err := dao.set(ctx, notDeleted(userID), bson.D{{Key: "isAdmin", Value: false}})
+	if err != nil {
 		return fmt.Errorf("%s: %w", op, err)
 	}
 
-	user.PassHash = newPasswordHash
+	if !matched {
+		return fmt.Errorf("%s: %w", op, storage.ErrUserNotFound)
+	}
+	return nil
+}
+
+// DisableUser blocks the user. Deleted users are not found.
+func (dao *UserDAO) DisableUser(ctx context.Context, userID int64) (err error) {
+	const op = "storage.mongo.DisableUser"
 
-	filter := bson.D{{Key: "_id", Value: userID}}
+	defer metrics.ObserveStorage("mongo", op, time.Now(), &err)
+
+	ctx, span := tracing.Start(ctx, op)
+	defer tracing.End(span, &err)
 
-	_, err = dao.c.ReplaceOne(ctx, filter, user)
+	matched, err := dao.set(ctx, notDeleted(userID), bson.D{{Key: "status", Value: models.UserStatusDisabled}})
 	if err != nil {
 		return fmt.Errorf("%s: %w", op, err)
 	}
+
+	if !matched {
+		return fmt.Errorf("%s: %w", op, storage.ErrUserNotFound)
+	}
 	return nil
 }
 
-func (dao *UserDAO) MakeAdmin(ctx context.Context, userID int64) error {
-	const op = "storage.mongo.MakeAdmin"
+// EnableUser makes the user active again. It also restores soft deleted users
+// that have not been purged yet.
+func (dao *UserDAO) EnableUser(ctx context.Context, userID int64) (err error) {
+	const op = "storage.mongo.EnableUser"
 
-	user, err := dao.findByID(ctx, userID)
+	defer metrics.ObserveStorage("mongo", op, time.Now(), &err)
+
+	ctx, span := tracing.Start(ctx, op)
+	defer tracing.End(span, &err)
+
+	matched, err := dao.set(ctx, byID(userID), bson.D{{Key: "status", Value: models.UserStatusActive}}, "deletedAt")
 	if err != nil {
-		if errors.Is(err, mongo.ErrNoDocuments) {
-			return fmt.Errorf("%s: %w", op, storage.ErrUserNotFound)
-		}
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if !matched {
+		return fmt.Errorf("%s: %w", op, storage.ErrUserNotFound)
+	}
+	return nil
+}
+
+// DeleteUser marks the user as deleted. The document is kept until
+// PurgeDeletedUsers removes it after the retention window. Users deleted
+// already are not found.
+func (dao *UserDAO) DeleteUser(ctx context.Context, userID int64) (err error) {
+	const op = "storage.mongo.DeleteUser"
 
+	defer metrics.ObserveStorage("mongo", op, time.Now(), &err)
+
+	ctx, span := tracing.Start(ctx, op)
+	defer tracing.End(span, &err)
+
+	matched, err := dao.set(ctx, notDeleted(userID), bson.D{
+		{Key: "status", Value: models.UserStatusDeleted},
+		{Key: "deletedAt", Value: time.Now().UTC()},
+	})
+	if err != nil {
 		return fmt.Errorf("%s: %w", op, err)
 	}
 
-	user.IsAdmin = true
+	if !matched {
+		return fmt.Errorf("%s: %w", op, storage.ErrUserNotFound)
+	}
+	return nil
+}
+
+// PurgeDeletedUsers permanently removes users soft deleted before the given time
+// and returns how many documents were removed.
+func (dao *UserDAO) PurgeDeletedUsers(ctx context.Context, before time.Time) (_ int64, err error) {
+	const op = "storage.mongo.PurgeDeletedUsers"
+
+	defer metrics.ObserveStorage("mongo", op, time.Now(), &err)
+
+	ctx, span := tracing.Start(ctx, op)
+	defer tracing.End(span, &err)
+
+	filter := bson.D{
+		{Key: "status", Value: models.UserStatusDeleted},
+		{Key: "deletedAt", Value: bson.D{{Key: "$lte", Value: before}}},
+	}
+
+	res, err := dao.c.DeleteMany(ctx, filter)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return res.DeletedCount, nil
+}
+
+// EraseUser permanently removes the user document regardless of its status.
+func (dao *UserDAO) EraseUser(ctx context.Context, userID int64) (err error) {
+	const op = "storage.mongo.EraseUser"
+
+	defer metrics.ObserveStorage("mongo", op, time.Now(), &err)
+
+	ctx, span := tracing.Start(ctx, op)
+	defer tracing.End(span, &err)
 
 	filter := bson.D{{Key: "_id", Value: userID}}
 
-	_, err = dao.c.ReplaceOne(ctx, filter, user)
+	res, err := dao.c.DeleteOne(ctx, filter)
 	if err != nil {
 		return fmt.Errorf("%s: %w", op, err)
 	}
+
+	if res.DeletedCount == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrUserNotFound)
+	}
+
 	return nil
 }
 
-func (dao *UserDAO) User(ctx context.Context, login string) (models.User, error) {
+func (dao *UserDAO) User(ctx context.Context, login string) (_ models.User, err error) {
 	const op = "storage.mongo.User"
 
+	defer metrics.ObserveStorage("mongo", op, time.Now(), &err)
+
+	ctx, span := tracing.Start(ctx, op)
+	defer tracing.End(span, &err)
+
 	filter := bson.D{{Key: "login", Value: login}}
 
 	var user models.User
 
-	err := dao.c.FindOne(ctx, filter).Decode(&user)
+	err = dao.c.FindOne(ctx, filter).Decode(&user)
+
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return models.User{}, fmt.Errorf("%s: %w", op, storage.ErrUserNotFound)
+		}
+
+		return models.User{}, fmt.Errorf("%s: %w", op, err)
+	}
+	return user, nil
+}
+
+func (dao *UserDAO) UserByID(ctx context.Context, userID int64) (_ models.User, err error) {
+	const op = "storage.mongo.UserByID"
 
+	defer metrics.ObserveStorage("mongo", op, time.Now(), &err)
+
+	ctx, span := tracing.Start(ctx, op)
+	defer tracing.End(span, &err)
+
+	user, err := dao.findByID(ctx, userID)
 	if err != nil {
 		if errors.Is(err, mongo.ErrNoDocuments) {
 			return models.User{}, fmt.Errorf("%s: %w", op, storage.ErrUserNotFound)
@@ -104,12 +269,17 @@ func (dao *UserDAO) User(ctx context.Context, login string) (models.User, error)
 	return user, nil
 }
 
-func (dao *UserDAO) IsAdmin(ctx context.Context, userID int64) (bool, error) {
+func (dao *UserDAO) IsAdmin(ctx context.Context, userID int64) (_ bool, err error) {
 	const op = "storage.mongo.IsAdmin"
 
+	defer metrics.ObserveStorage("mongo", op, time.Now(), &err)
+
+	ctx, span := tracing.Start(ctx, op)
+	defer tracing.End(span, &err)
+
 	var user models.User
 
-	user, err := dao.findByID(ctx, userID)
+	user, err = dao.findByID(ctx, userID)
 	if err != nil {
 		if errors.Is(err, mongo.ErrNoDocuments) {
 			return false, fmt.Errorf("%s: %w", op, storage.ErrUserNotFound)
@@ -120,10 +290,18 @@ func (dao *UserDAO) IsAdmin(ctx context.Context, userID int64) (bool, error) {
 	return user.IsAdmin, nil
 }
 
-func (dao *UserDAO) GetUserByTelegram(ctx context.Context, telegramLogin string) ([]models.User, error) {
+func (dao *UserDAO) GetUserByTelegram(ctx context.Context, telegramLogin string) (_ []models.User, err error) {
 	const op = "storage.mongo.GetUserByTelegram"
 
-	filter := bson.D{{Key: "telegramLogin", Value: telegramLogin}}
+	defer metrics.ObserveStorage("mongo", op, time.Now(), &err)
+
+	ctx, span := tracing.Start(ctx, op)
+	defer tracing.End(span, &err)
+
+	filter := bson.D{
+		{Key: "telegramLogin", Value: telegramLogin},
+		{Key: "status", Value: bson.D{{Key: "$ne", Value: models.UserStatusDeleted}}},
+	}
 
 	cursor, err := dao.c.Find(ctx, filter)
 
@@ -152,10 +330,15 @@ func (dao *UserDAO) GetUserByTelegram(ctx context.Context, telegramLogin string)
 	return users, nil
 }
 
-func (dao *UserDAO) GetAllUsers(ctx context.Context) ([]models.User, error) {
+func (dao *UserDAO) GetAllUsers(ctx context.Context) (_ []models.User, err error) {
 	const op = "storage.mongo.GetAllUsers"
 
-	filter := bson.D{}
+	defer metrics.ObserveStorage("mongo", op, time.Now(), &err)
+
+	ctx, span := tracing.Start(ctx, op)
+	defer tracing.End(span, &err)
+
+	filter := bson.D{{Key: "status", Value: bson.D{{Key: "$ne", Value: models.UserStatusDeleted}}}}
 
 	cursor, err := dao.c.Find(ctx, filter)
 
@@ -180,9 +363,14 @@ func (dao *UserDAO) GetAllUsers(ctx context.Context) ([]models.User, error) {
 	return users, nil
 }
 
-func (dao *UserDAO) EnsureIndexes(ctx context.Context) error {
+func (dao *UserDAO) EnsureIndexes(ctx context.Context) (err error) {
 	const op = "storage.mongo.EnsureIndexes"
 
+	defer metrics.ObserveStorage("mongo", op, time.Now(), &err)
+
+	ctx, span := tracing.Start(ctx, op)
+	defer tracing.End(span, &err)
+
 	indexModels := []mongo.IndexModel{
 		{
 			Keys:    bson.D{{Key: "login", Value: 1}},
@@ -191,9 +379,12 @@ func (dao *UserDAO) EnsureIndexes(ctx context.Context) error {
 		{
 			Keys: bson.D{{Key: "telegramLogin", Value: 1}},
 		},
+		{
+			Keys: bson.D{{Key: "status", Value: 1}, {Key: "deletedAt", Value: 1}},
+		},
 	}
 
-	_, err := dao.c.Indexes().CreateMany(ctx, indexModels)
+	_, err = dao.c.Indexes().CreateMany(ctx, indexModels)
 	if err != nil {
 		return fmt.Errorf("%s: %w", op, err)
 	}
@@ -201,6 +392,38 @@ func (dao *UserDAO) EnsureIndexes(ctx context.Context) error {
 	return nil
 }
 
+// insertIfFree inserts the user unless its ID is already taken. It upserts
+// instead of inserting so that a taken ID does not abort the surrounding
+// transaction. Duplicate logins still fail with a duplicate key error.
+func (dao *UserDAO) insertIfFree(ctx context.Context, user models.User) (bool, error) {
+	doc, err := bson.Marshal(user)
+	if err != nil {
+		return false, err
+	}
+
+	var fields bson.D
+	if err := bson.Unmarshal(doc, &fields); err != nil {
+		return false, err
+	}
+
+	// _id comes from the filter
+	fields = slices.DeleteFunc(fields, func(e bson.E) bool { return e.Key == "_id" })
+
+	filter := bson.D{{Key: "_id", Value: user.ID}}
+	update := bson.D{{Key: "$setOnInsert", Value: fields}}
+
+	res, err := dao.c.UpdateOne(ctx, filter, update, options.Update().SetUpsert(true))
+	if err != nil {
+		// a concurrent upsert of the same ID won the race
+		if isDuplicateID(err) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	return res.UpsertedCount == 1, nil
+}
+
 func (dao *UserDAO) findByID(ctx context.Context, userID int64) (models.User, error) {
 	filter := bson.D{{Key: "_id", Value: userID}}
 
@@ -213,3 +436,66 @@ func (dao *UserDAO) findByID(ctx context.Context, userID int64) (models.User, er
 	}
 	return user, nil
 }
+
+// set atomically applies fields to the user matched by filter, removes the
+// unset fields, bumps the version and updatedAt, and reports whether a user
+// matched. Fields of the document not mentioned are left untouched.
+func (dao *UserDAO) set(ctx context.Context, filter, fields bson.D, unset ...string) (bool, error) {
+	fields = append(fields, bson.E{Key: "updatedAt", Value: time.Now().UTC()})
+
+	update := bson.D{
+		{Key: "$set", Value: fields},
+		{Key: "$inc", Value: bson.D{{Key: "version", Value: 1}}},
+	}
+
+	if len(unset) > 0 {
+		removed := bson.D{}
+		for _, field := range unset {
+			removed = append(removed, bson.E{Key: field, Value: ""})
+		}
+		update = append(update, bson.E{Key: "$unset", Value: removed})
+	}
+
+	res, err := dao.c.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return false, err
+	}
+
+	return res.MatchedCount == 1, nil
+}
+
+func byID(userID int64) bson.D {
+	return bson.D{{Key: "_id", Value: userID}}
+}
+
+// notDeleted matches the user unless it is soft deleted.
+func notDeleted(userID int64) bson.D {
+	return bson.D{
+		{Key: "_id", Value: userID},
+		{Key: "status", Value: bson.D{{Key: "$ne", Value: models.UserStatusDeleted}}},
+	}
+}
+
+// isDuplicateID reports whether err is a duplicate key error on _id rather
+// than on one of the unique indexes, going by the key pattern the server
+// reports with the error.
+func isDuplicateID(err error) bool {
+	var we mongo.WriteException
+	if !mongo.IsDuplicateKeyError(err) || !errors.As(err, &we) {
+		return false
+	}
+
+	for _, e := range we.WriteErrors {
+		keyPattern, ok := e.Raw.Lookup("keyPattern").DocumentOK()
+		if !ok {
+			continue
+		}
+
+		keys, err := keyPattern.Elements()
+		if err == nil && len(keys) == 1 && keys[0].Key() == "_id" {
+			return true
+		}
+	}
+
+	return false
+}
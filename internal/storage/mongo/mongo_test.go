@@ -0,0 +1,63 @@
+package mongo
+
+import (
+	"errors"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func duplicateKey(t *testing.T, keyPattern bson.D) error {
+	t.Helper()
+
+	raw, err := bson.Marshal(bson.D{
+		{Key: "index", Value: 0},
+		{Key: "code", Value: 11000},
+		{Key: "keyPattern", Value: keyPattern},
+		{Key: "errmsg", Value: "E11000 duplicate key error"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return mongo.WriteException{WriteErrors: mongo.WriteErrors{{
+		Code:    11000,
+		Message: "E11000 duplicate key error",
+		Raw:     raw,
+	}}}
+}
+
+func TestIsDuplicateID(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{
+			name: "id",
+			err:  duplicateKey(t, bson.D{{Key: "_id", Value: 1}}),
+			want: true,
+		},
+		{
+			name: "unique index",
+			err:  duplicateKey(t, bson.D{{Key: "login", Value: 1}}),
+		},
+		{
+			name: "compound index with id",
+			err:  duplicateKey(t, bson.D{{Key: "_id", Value: 1}, {Key: "login", Value: 1}}),
+		},
+		{
+			name: "other error",
+			err:  errors.New("connection reset"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isDuplicateID(tt.err); got != tt.want {
+				t.Errorf("isDuplicateID() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,147 @@
+package mongo
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/j0n1que/sso-service/internal/domain/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// publishedRetention is how long published events stay in the outbox.
+const publishedRetention = 7 * 24 * time.Hour
+
+type outboxRecord struct {
+	models.Event `bson:",inline"`
+	PublishedAt  time.Time `bson:"publishedAt,omitempty"`
+	Attempts     int       `bson:"attempts"`
+	LastError    string    `bson:"lastError,omitempty"`
+}
+
+// OutboxDAO stores domain events until they are published. Events are added in
+// the same transaction as the change they describe.
+type OutboxDAO struct {
+	c *mongo.Collection
+}
+
+func NewOutboxDAO(ctx context.Context, db Database) *OutboxDAO {
+	return &OutboxDAO{
+		c: db.Collection("outbox"),
+	}
+}
+
+func (dao *OutboxDAO) Add(ctx context.Context, events ...models.Event) error {
+	const op = "storage.mongo.OutboxAdd"
+
+	records := make([]interface{}, len(events))
+	for i, event := range events {
+		records[i] = outboxRecord{Event: event}
+	}
+
+	if _, err := dao.c.InsertMany(ctx, records); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// Pending returns unpublished events in the order they occurred.
+func (dao *OutboxDAO) Pending(ctx context.Context, limit int64) ([]models.Event, error) {
+	const op = "storage.mongo.OutboxPending"
+
+	filter := bson.D{{Key: "publishedAt", Value: bson.D{{Key: "$exists", Value: false}}}}
+	opts := options.Find().
+		SetSort(bson.D{{Key: "occurredAt", Value: 1}, {Key: "_id", Value: 1}}).
+		SetLimit(limit)
+
+	cursor, err := dao.c.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	var records []outboxRecord
+	if err := cursor.All(ctx, &records); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	events := make([]models.Event, len(records))
+	for i := range records {
+		events[i] = records[i].Event
+	}
+
+	return events, nil
+}
+
+func (dao *OutboxDAO) MarkPublished(ctx context.Context, eventID string) error {
+	const op = "storage.mongo.OutboxMarkPublished"
+
+	filter := bson.D{{Key: "_id", Value: eventID}}
+	update := bson.D{
+		{Key: "$set", Value: bson.D{{Key: "publishedAt", Value: time.Now().UTC()}}},
+		{Key: "$inc", Value: bson.D{{Key: "attempts", Value: 1}}},
+	}
+
+	if _, err := dao.c.UpdateOne(ctx, filter, update); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (dao *OutboxDAO) MarkFailed(ctx context.Context, eventID string, reason string) error {
+	const op = "storage.mongo.OutboxMarkFailed"
+
+	filter := bson.D{{Key: "_id", Value: eventID}}
+	update := bson.D{
+		{Key: "$set", Value: bson.D{{Key: "lastError", Value: reason}}},
+		{Key: "$inc", Value: bson.D{{Key: "attempts", Value: 1}}},
+	}
+
+	if _, err := dao.c.UpdateOne(ctx, filter, update); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// EraseUser clears the personal data of the events of the user, published or
+// not.
+func (dao *OutboxDAO) EraseUser(ctx context.Context, userID int64) error {
+	const op = "storage.mongo.OutboxEraseUser"
+
+	filter := bson.D{{Key: "userId", Value: userID}}
+	update := bson.D{{Key: "$unset", Value: bson.D{
+		{Key: "login", Value: ""},
+		{Key: "telegramLogin", Value: ""},
+	}}}
+
+	if _, err := dao.c.UpdateMany(ctx, filter, update); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (dao *OutboxDAO) EnsureIndexes(ctx context.Context) error {
+	const op = "storage.mongo.OutboxEnsureIndexes"
+
+	indexModels := []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "publishedAt", Value: 1}, {Key: "occurredAt", Value: 1}},
+		},
+		{
+			Keys:    bson.D{{Key: "publishedAt", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(int32(publishedRetention.Seconds())),
+		},
+	}
+
+	_, err := dao.c.Indexes().CreateMany(ctx, indexModels)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
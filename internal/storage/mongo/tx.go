@@ -0,0 +1,69 @@
+package mongo
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/j0n1que/sso-service/internal/storage"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Transactor runs functions inside multi-document transactions. Transactions
+// need a replica set or a sharded cluster, on a standalone server the
+// functions run without one.
+type Transactor struct {
+	client    *mongo.Client
+	supported bool
+}
+
+func NewTransactor(ctx context.Context, client *mongo.Client) (*Transactor, error) {
+	const op = "storage.mongo.NewTransactor"
+
+	var hello struct {
+		SetName string `bson:"setName"`
+		Msg     string `bson:"msg"`
+	}
+
+	err := client.Database("admin").RunCommand(ctx, bson.D{{Key: "hello", Value: 1}}).Decode(&hello)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return &Transactor{
+		client:    client,
+		supported: hello.SetName != "" || hello.Msg == "isdbgrid",
+	}, nil
+}
+
+func (t *Transactor) Supported() bool {
+	return t.supported
+}
+
+func (t *Transactor) WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	if !t.supported {
+		return fn(ctx)
+	}
+
+	session, err := t.client.StartSession()
+	if err != nil {
+		return err
+	}
+	defer session.EndSession(ctx)
+
+	// hooks are collected anew on every attempt, since the driver retries fn
+	// on transient errors
+	var commit func()
+	_, err = session.WithTransaction(ctx, func(sc mongo.SessionContext) (interface{}, error) {
+		var hooksCtx context.Context
+		hooksCtx, commit = storage.WithCommitHooks(sc)
+		return nil, fn(hooksCtx)
+	})
+	if err != nil {
+		return err
+	}
+
+	commit()
+
+	return nil
+}
@@ -0,0 +1,259 @@
+package mongo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/j0n1que/sso-service/internal/domain/models"
+	"github.com/j0n1que/sso-service/internal/storage"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// deliveredRetention is how long successful deliveries are kept for inspection.
+const deliveredRetention = 7 * 24 * time.Hour
+
+type WebhookDAO struct {
+	webhooks   *mongo.Collection
+	deliveries *mongo.Collection
+}
+
+func NewWebhookDAO(ctx context.Context, db Database) *WebhookDAO {
+	return &WebhookDAO{
+		webhooks:   db.Collection("webhooks"),
+		deliveries: db.Collection("webhook_deliveries"),
+	}
+}
+
+func (dao *WebhookDAO) SaveWebhook(ctx context.Context, webhook models.Webhook) error {
+	const op = "storage.mongo.SaveWebhook"
+
+	if _, err := dao.webhooks.InsertOne(ctx, webhook); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (dao *WebhookDAO) DeleteWebhook(ctx context.Context, webhookID string) error {
+	const op = "storage.mongo.DeleteWebhook"
+
+	res, err := dao.webhooks.DeleteOne(ctx, bson.D{{Key: "_id", Value: webhookID}})
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if res.DeletedCount == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrWebhookNotFound)
+	}
+
+	return nil
+}
+
+func (dao *WebhookDAO) Webhook(ctx context.Context, webhookID string) (models.Webhook, error) {
+	const op = "storage.mongo.Webhook"
+
+	var webhook models.Webhook
+
+	err := dao.webhooks.FindOne(ctx, bson.D{{Key: "_id", Value: webhookID}}).Decode(&webhook)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return models.Webhook{}, fmt.Errorf("%s: %w", op, storage.ErrWebhookNotFound)
+		}
+
+		return models.Webhook{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return webhook, nil
+}
+
+func (dao *WebhookDAO) Webhooks(ctx context.Context) ([]models.Webhook, error) {
+	const op = "storage.mongo.Webhooks"
+
+	cursor, err := dao.webhooks.Find(ctx, bson.D{}, options.Find().SetSort(bson.D{{Key: "createdAt", Value: 1}}))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	var webhooks []models.Webhook
+	if err := cursor.All(ctx, &webhooks); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return webhooks, nil
+}
+
+// EnqueueDeliveries stores new deliveries. Deliveries that are already queued
+// are skipped, so enqueueing the same event twice is harmless.
+func (dao *WebhookDAO) EnqueueDeliveries(ctx context.Context, deliveries ...models.WebhookDelivery) error {
+	const op = "storage.mongo.EnqueueDeliveries"
+
+	docs := make([]interface{}, len(deliveries))
+	for i := range deliveries {
+		docs[i] = deliveries[i]
+	}
+
+	_, err := dao.deliveries.InsertMany(ctx, docs, options.InsertMany().SetOrdered(false))
+	if err != nil && !onlyDuplicates(err) {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// ClaimDueDelivery picks a pending delivery whose next attempt is due and
+// postpones it by lease so that other workers skip it while it is in flight.
+// It returns storage.ErrDeliveryNotFound when nothing is due.
+func (dao *WebhookDAO) ClaimDueDelivery(ctx context.Context, now time.Time, lease time.Duration) (models.WebhookDelivery, error) {
+	const op = "storage.mongo.ClaimDueDelivery"
+
+	filter := bson.D{
+		{Key: "status", Value: models.DeliveryStatusPending},
+		{Key: "nextAttemptAt", Value: bson.D{{Key: "$lte", Value: now}}},
+	}
+	update := bson.D{{Key: "$set", Value: bson.D{{Key: "nextAttemptAt", Value: now.Add(lease)}}}}
+	opts := options.FindOneAndUpdate().
+		SetSort(bson.D{{Key: "nextAttemptAt", Value: 1}}).
+		SetReturnDocument(options.Before)
+
+	var delivery models.WebhookDelivery
+
+	err := dao.deliveries.FindOneAndUpdate(ctx, filter, update, opts).Decode(&delivery)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return models.WebhookDelivery{}, fmt.Errorf("%s: %w", op, storage.ErrDeliveryNotFound)
+		}
+
+		return models.WebhookDelivery{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return delivery, nil
+}
+
+// RecordAttempt stores the outcome of a delivery attempt together with the new
+// status and the time of the next attempt.
+func (dao *WebhookDAO) RecordAttempt(ctx context.Context, deliveryID string, attempt models.DeliveryAttempt, status string, nextAttemptAt time.Time) error {
+	const op = "storage.mongo.RecordAttempt"
+
+	filter := bson.D{{Key: "_id", Value: deliveryID}}
+	update := bson.D{
+		{Key: "$set", Value: bson.D{
+			{Key: "status", Value: status},
+			{Key: "nextAttemptAt", Value: nextAttemptAt},
+			{Key: "lastError", Value: attempt.Error},
+		}},
+		{Key: "$inc", Value: bson.D{{Key: "attempts", Value: 1}}},
+		{Key: "$push", Value: bson.D{{Key: "history", Value: attempt}}},
+	}
+
+	if _, err := dao.deliveries.UpdateOne(ctx, filter, update); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (dao *WebhookDAO) DeadDeliveries(ctx context.Context, limit int64) ([]models.WebhookDelivery, error) {
+	const op = "storage.mongo.DeadDeliveries"
+
+	filter := bson.D{{Key: "status", Value: models.DeliveryStatusDead}}
+	opts := options.Find().SetSort(bson.D{{Key: "createdAt", Value: -1}}).SetLimit(limit)
+
+	cursor, err := dao.deliveries.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	var deliveries []models.WebhookDelivery
+	if err := cursor.All(ctx, &deliveries); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return deliveries, nil
+}
+
+// ReplayDelivery moves a dead delivery back to the queue with a fresh attempt
+// budget. The attempt history is kept.
+func (dao *WebhookDAO) ReplayDelivery(ctx context.Context, deliveryID string) error {
+	const op = "storage.mongo.ReplayDelivery"
+
+	filter := bson.D{
+		{Key: "_id", Value: deliveryID},
+		{Key: "status", Value: models.DeliveryStatusDead},
+	}
+	update := bson.D{{Key: "$set", Value: bson.D{
+		{Key: "status", Value: models.DeliveryStatusPending},
+		{Key: "attempts", Value: 0},
+		{Key: "nextAttemptAt", Value: time.Now().UTC()},
+	}}}
+
+	res, err := dao.deliveries.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if res.MatchedCount == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrDeliveryNotFound)
+	}
+
+	return nil
+}
+
+// EraseUserDeliveries clears the personal data of the events of the user in
+// every delivery, whatever its status.
+func (dao *WebhookDAO) EraseUserDeliveries(ctx context.Context, userID int64) error {
+	const op = "storage.mongo.EraseUserDeliveries"
+
+	filter := bson.D{{Key: "event.userId", Value: userID}}
+	update := bson.D{{Key: "$unset", Value: bson.D{
+		{Key: "event.login", Value: ""},
+		{Key: "event.telegramLogin", Value: ""},
+	}}}
+
+	if _, err := dao.deliveries.UpdateMany(ctx, filter, update); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (dao *WebhookDAO) EnsureIndexes(ctx context.Context) error {
+	const op = "storage.mongo.WebhookEnsureIndexes"
+
+	indexModels := []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "status", Value: 1}, {Key: "nextAttemptAt", Value: 1}},
+		},
+		{
+			Keys: bson.D{{Key: "createdAt", Value: 1}},
+			Options: options.Index().
+				SetExpireAfterSeconds(int32(deliveredRetention.Seconds())).
+				SetPartialFilterExpression(bson.D{{Key: "status", Value: models.DeliveryStatusDelivered}}),
+		},
+	}
+
+	_, err := dao.deliveries.Indexes().CreateMany(ctx, indexModels)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func onlyDuplicates(err error) bool {
+	var bwe mongo.BulkWriteException
+	if !errors.As(err, &bwe) || bwe.WriteConcernError != nil || len(bwe.WriteErrors) == 0 {
+		return false
+	}
+
+	for _, we := range bwe.WriteErrors {
+		if !mongo.IsDuplicateKeyError(we) {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,201 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/j0n1que/sso-service/internal/domain/models"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// maxAppendAttempts bounds retries when concurrent writers race for the same
+// sequence number.
+const maxAppendAttempts = 5
+
+const auditColumns = "seq, timestamp, actor_id, target_id, action, outcome, reason, ip, user_agent, prev_hash, hash"
+
+// AuditDAO stores the append-only audit log with the same sequence numbering
+// and hash chaining as the Mongo implementation.
+type AuditDAO struct {
+	pool      *pgxpool.Pool
+	hashChain bool
+}
+
+func NewAuditDAO(pool *pgxpool.Pool, hashChain bool) *AuditDAO {
+	return &AuditDAO{
+		pool:      pool,
+		hashChain: hashChain,
+	}
+}
+
+func (dao *AuditDAO) Append(ctx context.Context, entry models.AuditEntry) error {
+	const op = "storage.postgres.AuditAppend"
+
+	// truncate like the Mongo implementation so hashes match across backends
+	entry.Timestamp = entry.Timestamp.UTC().Truncate(time.Millisecond)
+
+	for attempt := 0; attempt < maxAppendAttempts; attempt++ {
+		last, err := dao.last(ctx)
+		if err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+
+		entry.Seq = last.Seq + 1
+		if dao.hashChain {
+			entry.PrevHash = last.Hash
+			entry.Hash = entry.ChainHash()
+		}
+
+		_, err = conn(ctx, dao.pool).Exec(ctx,
+			"INSERT INTO audit_log ("+auditColumns+") VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)",
+			entry.Seq, entry.Timestamp, entry.ActorID, entry.TargetID, entry.Action, entry.Outcome,
+			entry.Reason, entry.IP, entry.UserAgent, entry.PrevHash, entry.Hash,
+		)
+		if err == nil {
+			return nil
+		}
+		if !isUniqueViolation(err) {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	return fmt.Errorf("%s: sequence conflict after %d attempts", op, maxAppendAttempts)
+}
+
+func (dao *AuditDAO) Query(ctx context.Context, filter models.AuditFilter) ([]models.AuditEntry, error) {
+	const op = "storage.postgres.AuditQuery"
+
+	var (
+		conds []string
+		args  []any
+	)
+	where := func(cond string, arg any) {
+		args = append(args, arg)
+		conds = append(conds, strings.ReplaceAll(cond, "?", "$"+strconv.Itoa(len(args))))
+	}
+
+	if filter.ActorID != 0 {
+		where("actor_id = ?", filter.ActorID)
+	}
+	if filter.TargetID != 0 {
+		where("target_id = ?", filter.TargetID)
+	}
+	if filter.UserID != 0 {
+		where("(actor_id = ? OR target_id = ?)", filter.UserID)
+	}
+	if filter.Action != "" {
+		where("action = ?", filter.Action)
+	}
+	if filter.Outcome != "" {
+		where("outcome = ?", filter.Outcome)
+	}
+	if !filter.Since.IsZero() {
+		where("timestamp >= ?", filter.Since)
+	}
+	if !filter.Until.IsZero() {
+		where("timestamp < ?", filter.Until)
+	}
+	if filter.AfterSeq != 0 {
+		where("seq < ?", filter.AfterSeq)
+	}
+
+	sql := "SELECT " + auditColumns + " FROM audit_log"
+	if len(conds) > 0 {
+		sql += " WHERE " + strings.Join(conds, " AND ")
+	}
+	sql += " ORDER BY seq DESC"
+	if filter.Limit > 0 {
+		sql += " LIMIT " + strconv.FormatInt(filter.Limit, 10)
+	}
+
+	rows, err := conn(ctx, dao.pool).Query(ctx, sql, args...)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var entries []models.AuditEntry
+
+	for rows.Next() {
+		entry, err := scanAuditEntry(rows)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		entries = append(entries, entry)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return entries, nil
+}
+
+// Verify walks the whole log in order and checks sequence continuity and the
+// hash chain of the entries written with one. It returns the number of checked entries and the sequence number
+// of the first broken entry, or zero if the log is intact.
+func (dao *AuditDAO) Verify(ctx context.Context) (int64, int64, error) {
+	const op = "storage.postgres.AuditVerify"
+
+	rows, err := conn(ctx, dao.pool).Query(ctx, "SELECT "+auditColumns+" FROM audit_log ORDER BY seq")
+	if err != nil {
+		return 0, 0, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var (
+		checked int64
+		chain   = models.AuditChain{Chaining: dao.hashChain}
+	)
+
+	for rows.Next() {
+		entry, err := scanAuditEntry(rows)
+		if err != nil {
+			return checked, 0, fmt.Errorf("%s: %w", op, err)
+		}
+
+		if broken := chain.Check(entry); broken != 0 {
+			return checked, broken, nil
+		}
+
+		checked++
+	}
+
+	if err := rows.Err(); err != nil {
+		return checked, 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return checked, 0, nil
+}
+
+func (dao *AuditDAO) last(ctx context.Context) (models.AuditEntry, error) {
+	row := conn(ctx, dao.pool).QueryRow(ctx, "SELECT "+auditColumns+" FROM audit_log ORDER BY seq DESC LIMIT 1")
+
+	entry, err := scanAuditEntry(row)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return models.AuditEntry{}, err
+	}
+
+	return entry, nil
+}
+
+func scanAuditEntry(row pgx.Row) (models.AuditEntry, error) {
+	var entry models.AuditEntry
+
+	err := row.Scan(
+		&entry.Seq, &entry.Timestamp, &entry.ActorID, &entry.TargetID, &entry.Action, &entry.Outcome,
+		&entry.Reason, &entry.IP, &entry.UserAgent, &entry.PrevHash, &entry.Hash,
+	)
+	if err != nil {
+		return models.AuditEntry{}, err
+	}
+
+	entry.Timestamp = entry.Timestamp.UTC()
+
+	return entry, nil
+}
@@ -0,0 +1,113 @@
+package postgres
+
+import (
+	"context"
+	"embed"
+	"fmt"
+	"io/fs"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+//go:embed migrations/*.sql
+var migrations embed.FS
+
+// migrationLockID is the advisory lock key held while migrating, so that only
+// one replica applies migrations at a time.
+const migrationLockID int64 = 7_351_026_114
+
+func Connect(ctx context.Context, dsn string, maxConns int32) (*pgxpool.Pool, error) {
+	const op = "storage.postgres.Connect"
+
+	cfg, err := pgxpool.ParseConfig(dsn)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	if maxConns > 0 {
+		cfg.MaxConns = maxConns
+	}
+
+	pool, err := pgxpool.NewWithConfig(ctx, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := pool.Ping(ctx); err != nil {
+		pool.Close()
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return pool, nil
+}
+
+// Migrate applies the embedded migrations that have not been applied yet, in
+// the order of their numeric prefix. Migration files are named like
+// 0001_create_users.sql.
+func Migrate(ctx context.Context, pool *pgxpool.Pool) error {
+	const op = "storage.postgres.Migrate"
+
+	files, err := fs.Glob(migrations, "migrations/*.sql")
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	sort.Strings(files)
+
+	err = pgx.BeginFunc(ctx, pool, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, "SELECT pg_advisory_xact_lock($1)", migrationLockID); err != nil {
+			return err
+		}
+
+		_, err := tx.Exec(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+			version    BIGINT PRIMARY KEY,
+			name       TEXT NOT NULL,
+			applied_at TIMESTAMPTZ NOT NULL DEFAULT now()
+		)`)
+		if err != nil {
+			return err
+		}
+
+		for _, file := range files {
+			name := strings.TrimSuffix(strings.TrimPrefix(file, "migrations/"), ".sql")
+
+			prefix, _, _ := strings.Cut(name, "_")
+			version, err := strconv.ParseInt(prefix, 10, 64)
+			if err != nil {
+				return fmt.Errorf("migration %s: bad version: %w", name, err)
+			}
+
+			var applied bool
+			err = tx.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM schema_migrations WHERE version = $1)", version).Scan(&applied)
+			if err != nil {
+				return err
+			}
+			if applied {
+				continue
+			}
+
+			script, err := migrations.ReadFile(file)
+			if err != nil {
+				return err
+			}
+
+			if _, err := tx.Exec(ctx, string(script)); err != nil {
+				return fmt.Errorf("migration %s: %w", name, err)
+			}
+
+			_, err = tx.Exec(ctx, "INSERT INTO schema_migrations (version, name) VALUES ($1, $2)", version, name)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,13 @@
+CREATE TABLE users (
+    id             BIGINT PRIMARY KEY,
+    login          TEXT NOT NULL,
+    pass_hash      BYTEA NOT NULL,
+    is_admin       BOOLEAN NOT NULL DEFAULT FALSE,
+    telegram_login TEXT NOT NULL DEFAULT '',
+    status         TEXT NOT NULL DEFAULT 'active',
+    deleted_at     TIMESTAMPTZ,
+    CONSTRAINT users_login_key UNIQUE (login)
+);
+
+CREATE INDEX users_telegram_login_idx ON users (telegram_login);
+CREATE INDEX users_status_deleted_at_idx ON users (status, deleted_at);
@@ -0,0 +1,17 @@
+CREATE TABLE audit_log (
+    seq        BIGINT PRIMARY KEY,
+    timestamp  TIMESTAMPTZ NOT NULL,
+    actor_id   BIGINT NOT NULL DEFAULT 0,
+    target_id  BIGINT NOT NULL DEFAULT 0,
+    action     TEXT NOT NULL,
+    outcome    TEXT NOT NULL,
+    reason     TEXT NOT NULL DEFAULT '',
+    ip         TEXT NOT NULL DEFAULT '',
+    user_agent TEXT NOT NULL DEFAULT '',
+    prev_hash  TEXT NOT NULL DEFAULT '',
+    hash       TEXT NOT NULL DEFAULT ''
+);
+
+CREATE INDEX audit_log_actor_id_idx ON audit_log (actor_id);
+CREATE INDEX audit_log_target_id_idx ON audit_log (target_id);
+CREATE INDEX audit_log_action_timestamp_idx ON audit_log (action, timestamp DESC);
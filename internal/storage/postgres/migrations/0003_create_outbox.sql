@@ -0,0 +1,15 @@
+CREATE TABLE outbox (
+    id             TEXT PRIMARY KEY,
+    type           TEXT NOT NULL,
+    user_id        BIGINT NOT NULL,
+    actor_id       BIGINT NOT NULL DEFAULT 0,
+    occurred_at    TIMESTAMPTZ NOT NULL,
+    login          TEXT NOT NULL DEFAULT '',
+    telegram_login TEXT NOT NULL DEFAULT '',
+    published_at   TIMESTAMPTZ,
+    attempts       INTEGER NOT NULL DEFAULT 0,
+    last_error     TEXT NOT NULL DEFAULT ''
+);
+
+CREATE INDEX outbox_pending_idx ON outbox (occurred_at, id) WHERE published_at IS NULL;
+CREATE INDEX outbox_published_at_idx ON outbox (published_at);
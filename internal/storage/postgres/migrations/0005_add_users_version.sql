@@ -0,0 +1,3 @@
+ALTER TABLE users
+    ADD COLUMN version    BIGINT NOT NULL DEFAULT 0,
+    ADD COLUMN updated_at TIMESTAMPTZ;
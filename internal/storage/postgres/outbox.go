@@ -0,0 +1,127 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/j0n1que/sso-service/internal/domain/models"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// publishedRetention is how long published events stay in the outbox.
+const publishedRetention = 7 * 24 * time.Hour
+
+// OutboxDAO stores domain events until they are published. Events are added in
+// the same transaction as the change they describe.
+type OutboxDAO struct {
+	pool *pgxpool.Pool
+}
+
+func NewOutboxDAO(pool *pgxpool.Pool) *OutboxDAO {
+	return &OutboxDAO{pool: pool}
+}
+
+func (dao *OutboxDAO) Add(ctx context.Context, events ...models.Event) error {
+	const op = "storage.postgres.OutboxAdd"
+
+	batch := &pgx.Batch{}
+	for _, event := range events {
+		batch.Queue(
+			"INSERT INTO outbox (id, type, user_id, actor_id, occurred_at, login, telegram_login) VALUES ($1, $2, $3, $4, $5, $6, $7)",
+			event.ID, event.Type, event.UserID, event.ActorID, event.OccurredAt, event.Login, event.TelegramLogin,
+		)
+	}
+
+	if err := conn(ctx, dao.pool).SendBatch(ctx, batch).Close(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// Pending returns unpublished events in the order they occurred. Published
+// events past their retention are removed on the way.
+func (dao *OutboxDAO) Pending(ctx context.Context, limit int64) ([]models.Event, error) {
+	const op = "storage.postgres.OutboxPending"
+
+	db := conn(ctx, dao.pool)
+
+	_, err := db.Exec(ctx, "DELETE FROM outbox WHERE published_at < $1", time.Now().UTC().Add(-publishedRetention))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	rows, err := db.Query(ctx,
+		`SELECT id, type, user_id, actor_id, occurred_at, login, telegram_login FROM outbox
+		WHERE published_at IS NULL ORDER BY occurred_at, id LIMIT $1`,
+		limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var events []models.Event
+
+	for rows.Next() {
+		var event models.Event
+		err := rows.Scan(&event.ID, &event.Type, &event.UserID, &event.ActorID, &event.OccurredAt, &event.Login, &event.TelegramLogin)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		event.OccurredAt = event.OccurredAt.UTC()
+		events = append(events, event)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return events, nil
+}
+
+// EraseUser clears the personal data of the events of the user, published or
+// not.
+func (dao *OutboxDAO) EraseUser(ctx context.Context, userID int64) error {
+	const op = "storage.postgres.OutboxEraseUser"
+
+	_, err := conn(ctx, dao.pool).Exec(ctx,
+		"UPDATE outbox SET login = '', telegram_login = '' WHERE user_id = $1 AND (login <> '' OR telegram_login <> '')",
+		userID,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (dao *OutboxDAO) MarkPublished(ctx context.Context, eventID string) error {
+	const op = "storage.postgres.OutboxMarkPublished"
+
+	_, err := conn(ctx, dao.pool).Exec(ctx,
+		"UPDATE outbox SET published_at = $2, attempts = attempts + 1 WHERE id = $1",
+		eventID, time.Now().UTC(),
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (dao *OutboxDAO) MarkFailed(ctx context.Context, eventID string, reason string) error {
+	const op = "storage.postgres.OutboxMarkFailed"
+
+	_, err := conn(ctx, dao.pool).Exec(ctx,
+		"UPDATE outbox SET last_error = $2, attempts = attempts + 1 WHERE id = $1",
+		eventID, reason,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,349 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/j0n1que/sso-service/internal/domain/models"
+	"github.com/j0n1que/sso-service/internal/lib/metrics"
+	"github.com/j0n1que/sso-service/internal/lib/tracing"
+	"github.com/j0n1que/sso-service/internal/lib/userid"
+	"github.com/j0n1que/sso-service/internal/storage"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const uniqueViolation = "23505"
+
+const userColumns = "id, login, pass_hash, is_admin, telegram_login, status, deleted_at, version, updated_at"
+
+type UserDAO struct {
+	pool *pgxpool.Pool
+}
+
+func New(pool *pgxpool.Pool) *UserDAO {
+	return &UserDAO{pool: pool}
+}
+
+func (dao *UserDAO) SaveUser(ctx context.Context, user models.User) (_ int64, err error) {
+	const op = "storage.postgres.SaveUser"
+
+	defer metrics.ObserveStorage("postgres", op, time.Now(), &err)
+
+	ctx, span := tracing.Start(ctx, op)
+	defer tracing.End(span, &err)
+
+	// a taken ID is skipped instead of failing, so that the surrounding
+	// transaction is not aborted and the insert can be retried with a new ID
+	for attempt := 0; attempt < userid.MaxAttempts; attempt++ {
+		user.ID = userid.New()
+
+		tag, err := conn(ctx, dao.pool).Exec(ctx,
+			"INSERT INTO users (id, login, pass_hash, is_admin, telegram_login, status, version, updated_at) VALUES ($1, $2, $3, $4, $5, $6, 1, now()) ON CONFLICT (id) DO NOTHING",
+			user.ID, user.Login, user.PassHash, user.IsAdmin, user.TelegramLogin, user.Status,
+		)
+		if err != nil {
+			if isUniqueViolation(err) {
+				return 0, fmt.Errorf("%s: %w", op, storage.ErrUserExists)
+			}
+			return 0, fmt.Errorf("%s: %w", op, err)
+		}
+
+		if tag.RowsAffected() == 1 {
+			return user.ID, nil
+		}
+	}
+
+	return 0, fmt.Errorf("%s: %w", op, storage.ErrIDCollision)
+}
+
+func (dao *UserDAO) ChangePassword(ctx context.Context, userID int64, newPasswordHash []byte) (err error) {
+	const op = "storage.postgres.ChangePassword"
+
+	defer metrics.ObserveStorage("postgres", op, time.Now(), &err)
+
+	ctx, span := tracing.Start(ctx, op)
+	defer tracing.End(span, &err)
+
+	return dao.update(ctx, op,
+		"UPDATE users SET version = version + 1, updated_at = now(), pass_hash = $2 WHERE id = $1 AND status <> $3",
+		userID, newPasswordHash, models.UserStatusDeleted,
+	)
+}
+
+func (dao *UserDAO) MakeAdmin(ctx context.Context, userID int64) (err error) {
+	const op = "storage.postgres.MakeAdmin"
+
+	defer metrics.ObserveStorage("postgres", op, time.Now(), &err)
+
+	ctx, span := tracing.Start(ctx, op)
+	defer tracing.End(span, &err)
+
+	return dao.update(ctx, op,
+		"UPDATE users SET version = version + 1, updated_at = now(), is_admin = TRUE WHERE id = $1 AND status <> $2",
+		userID, models.UserStatusDeleted,
+	)
+}
+
+func (dao *UserDAO) RevokeAdmin(ctx context.Context, userID int64) (err error) {
+	const op = "storage.postgres.RevokeAdmin"
+
+	defer metrics.ObserveStorage("postgres", op, time.Now(), &err)
+
+	ctx, span := tracing.Start(ctx, op)
+	defer tracing.End(span, &err)
+
+	return dao.update(ctx, op,
+		"UPDATE users SET version = version + 1, updated_at = now(), is_admin = FALSE WHERE id = $1 AND status <> $2",
+		userID, models.UserStatusDeleted,
+	)
+}
+
+func (dao *UserDAO) DisableUser(ctx context.Context, userID int64) (err error) {
+	const op = "storage.postgres.DisableUser"
+
+	defer metrics.ObserveStorage("postgres", op, time.Now(), &err)
+
+	ctx, span := tracing.Start(ctx, op)
+	defer tracing.End(span, &err)
+
+	return dao.update(ctx, op,
+		"UPDATE users SET version = version + 1, updated_at = now(), status = $2 WHERE id = $1 AND status <> $3",
+		userID, models.UserStatusDisabled, models.UserStatusDeleted,
+	)
+}
+
+// EnableUser makes the user active again. It also restores soft deleted users
+// that have not been purged yet.
+func (dao *UserDAO) EnableUser(ctx context.Context, userID int64) (err error) {
+	const op = "storage.postgres.EnableUser"
+
+	defer metrics.ObserveStorage("postgres", op, time.Now(), &err)
+
+	ctx, span := tracing.Start(ctx, op)
+	defer tracing.End(span, &err)
+
+	return dao.update(ctx, op,
+		"UPDATE users SET version = version + 1, updated_at = now(), status = $2, deleted_at = NULL WHERE id = $1",
+		userID, models.UserStatusActive,
+	)
+}
+
+// DeleteUser marks the user as deleted. The row is kept until
+// PurgeDeletedUsers removes it after the retention window.
+func (dao *UserDAO) DeleteUser(ctx context.Context, userID int64) (err error) {
+	const op = "storage.postgres.DeleteUser"
+
+	defer metrics.ObserveStorage("postgres", op, time.Now(), &err)
+
+	ctx, span := tracing.Start(ctx, op)
+	defer tracing.End(span, &err)
+
+	return dao.update(ctx, op,
+		"UPDATE users SET version = version + 1, updated_at = now(), status = $2, deleted_at = $3 WHERE id = $1 AND status <> $2",
+		userID, models.UserStatusDeleted, time.Now().UTC(),
+	)
+}
+
+// PurgeDeletedUsers permanently removes users soft deleted before the given time
+// and returns how many rows were removed.
+func (dao *UserDAO) PurgeDeletedUsers(ctx context.Context, before time.Time) (_ int64, err error) {
+	const op = "storage.postgres.PurgeDeletedUsers"
+
+	defer metrics.ObserveStorage("postgres", op, time.Now(), &err)
+
+	ctx, span := tracing.Start(ctx, op)
+	defer tracing.End(span, &err)
+
+	tag, err := conn(ctx, dao.pool).Exec(ctx,
+		"DELETE FROM users WHERE status = $1 AND deleted_at <= $2",
+		models.UserStatusDeleted, before,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return tag.RowsAffected(), nil
+}
+
+// EraseUser permanently removes the user regardless of its status.
+func (dao *UserDAO) EraseUser(ctx context.Context, userID int64) (err error) {
+	const op = "storage.postgres.EraseUser"
+
+	defer metrics.ObserveStorage("postgres", op, time.Now(), &err)
+
+	ctx, span := tracing.Start(ctx, op)
+	defer tracing.End(span, &err)
+
+	return dao.update(ctx, op, "DELETE FROM users WHERE id = $1", userID)
+}
+
+func (dao *UserDAO) User(ctx context.Context, login string) (_ models.User, err error) {
+	const op = "storage.postgres.User"
+
+	defer metrics.ObserveStorage("postgres", op, time.Now(), &err)
+
+	ctx, span := tracing.Start(ctx, op)
+	defer tracing.End(span, &err)
+
+	row := conn(ctx, dao.pool).QueryRow(ctx, "SELECT "+userColumns+" FROM users WHERE login = $1", login)
+
+	user, err := scanUser(row)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return models.User{}, fmt.Errorf("%s: %w", op, storage.ErrUserNotFound)
+		}
+
+		return models.User{}, fmt.Errorf("%s: %w", op, err)
+	}
+	return user, nil
+}
+
+func (dao *UserDAO) UserByID(ctx context.Context, userID int64) (_ models.User, err error) {
+	const op = "storage.postgres.UserByID"
+
+	defer metrics.ObserveStorage("postgres", op, time.Now(), &err)
+
+	ctx, span := tracing.Start(ctx, op)
+	defer tracing.End(span, &err)
+
+	row := conn(ctx, dao.pool).QueryRow(ctx, "SELECT "+userColumns+" FROM users WHERE id = $1", userID)
+
+	user, err := scanUser(row)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return models.User{}, fmt.Errorf("%s: %w", op, storage.ErrUserNotFound)
+		}
+
+		return models.User{}, fmt.Errorf("%s: %w", op, err)
+	}
+	return user, nil
+}
+
+func (dao *UserDAO) IsAdmin(ctx context.Context, userID int64) (_ bool, err error) {
+	const op = "storage.postgres.IsAdmin"
+
+	defer metrics.ObserveStorage("postgres", op, time.Now(), &err)
+
+	ctx, span := tracing.Start(ctx, op)
+	defer tracing.End(span, &err)
+
+	var isAdmin bool
+
+	err = conn(ctx, dao.pool).QueryRow(ctx, "SELECT is_admin FROM users WHERE id = $1", userID).Scan(&isAdmin)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return false, fmt.Errorf("%s: %w", op, storage.ErrUserNotFound)
+		}
+
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+	return isAdmin, nil
+}
+
+func (dao *UserDAO) GetUserByTelegram(ctx context.Context, telegramLogin string) (_ []models.User, err error) {
+	const op = "storage.postgres.GetUserByTelegram"
+
+	defer metrics.ObserveStorage("postgres", op, time.Now(), &err)
+
+	ctx, span := tracing.Start(ctx, op)
+	defer tracing.End(span, &err)
+
+	users, err := dao.queryUsers(ctx,
+		"SELECT "+userColumns+" FROM users WHERE telegram_login = $1 AND status <> $2",
+		telegramLogin, models.UserStatusDeleted,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return users, nil
+}
+
+func (dao *UserDAO) GetAllUsers(ctx context.Context) (_ []models.User, err error) {
+	const op = "storage.postgres.GetAllUsers"
+
+	defer metrics.ObserveStorage("postgres", op, time.Now(), &err)
+
+	ctx, span := tracing.Start(ctx, op)
+	defer tracing.End(span, &err)
+
+	users, err := dao.queryUsers(ctx,
+		"SELECT "+userColumns+" FROM users WHERE status <> $1 ORDER BY id",
+		models.UserStatusDeleted,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return users, nil
+}
+
+// update runs a statement changing a single user and reports
+// storage.ErrUserNotFound when no row matched.
+func (dao *UserDAO) update(ctx context.Context, op, sql string, args ...any) error {
+	tag, err := conn(ctx, dao.pool).Exec(ctx, sql, args...)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrUserNotFound)
+	}
+
+	return nil
+}
+
+func (dao *UserDAO) queryUsers(ctx context.Context, sql string, args ...any) ([]models.User, error) {
+	rows, err := conn(ctx, dao.pool).Query(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var users []models.User
+
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, user)
+	}
+
+	return users, rows.Err()
+}
+
+func scanUser(row pgx.Row) (models.User, error) {
+	var (
+		user      models.User
+		deletedAt *time.Time
+		updatedAt *time.Time
+	)
+
+	err := row.Scan(
+		&user.ID, &user.Login, &user.PassHash, &user.IsAdmin, &user.TelegramLogin, &user.Status,
+		&deletedAt, &user.Version, &updatedAt,
+	)
+	if err != nil {
+		return models.User{}, err
+	}
+
+	if deletedAt != nil {
+		user.DeletedAt = deletedAt.UTC()
+	}
+	if updatedAt != nil {
+		user.UpdatedAt = updatedAt.UTC()
+	}
+
+	return user, nil
+}
+
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+
+	return errors.As(err, &pgErr) && pgErr.Code == uniqueViolation
+}
@@ -0,0 +1,307 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/j0n1que/sso-service/internal/domain/models"
+	"github.com/j0n1que/sso-service/internal/storage"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// deliveredRetention is how long successful deliveries are kept for inspection.
+const deliveredRetention = 7 * 24 * time.Hour
+
+const deliveryColumns = "id, webhook_id, event, status, attempts, next_attempt_at, last_error, history, created_at"
+
+type WebhookDAO struct {
+	pool *pgxpool.Pool
+}
+
+func NewWebhookDAO(pool *pgxpool.Pool) *WebhookDAO {
+	return &WebhookDAO{pool: pool}
+}
+
+func (dao *WebhookDAO) SaveWebhook(ctx context.Context, webhook models.Webhook) error {
+	const op = "storage.postgres.SaveWebhook"
+
+	eventTypes := webhook.EventTypes
+	if eventTypes == nil {
+		eventTypes = []string{}
+	}
+
+	_, err := conn(ctx, dao.pool).Exec(ctx,
+		"INSERT INTO webhooks (id, url, secret, event_types, created_by, created_at) VALUES ($1, $2, $3, $4, $5, $6)",
+		webhook.ID, webhook.URL, webhook.Secret, eventTypes, webhook.CreatedBy, webhook.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (dao *WebhookDAO) DeleteWebhook(ctx context.Context, webhookID string) error {
+	const op = "storage.postgres.DeleteWebhook"
+
+	tag, err := conn(ctx, dao.pool).Exec(ctx, "DELETE FROM webhooks WHERE id = $1", webhookID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrWebhookNotFound)
+	}
+
+	return nil
+}
+
+func (dao *WebhookDAO) Webhook(ctx context.Context, webhookID string) (models.Webhook, error) {
+	const op = "storage.postgres.Webhook"
+
+	row := conn(ctx, dao.pool).QueryRow(ctx,
+		"SELECT id, url, secret, event_types, created_by, created_at FROM webhooks WHERE id = $1",
+		webhookID,
+	)
+
+	webhook, err := scanWebhook(row)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return models.Webhook{}, fmt.Errorf("%s: %w", op, storage.ErrWebhookNotFound)
+		}
+
+		return models.Webhook{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return webhook, nil
+}
+
+func (dao *WebhookDAO) Webhooks(ctx context.Context) ([]models.Webhook, error) {
+	const op = "storage.postgres.Webhooks"
+
+	rows, err := conn(ctx, dao.pool).Query(ctx,
+		"SELECT id, url, secret, event_types, created_by, created_at FROM webhooks ORDER BY created_at",
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var webhooks []models.Webhook
+
+	for rows.Next() {
+		webhook, err := scanWebhook(rows)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		webhooks = append(webhooks, webhook)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return webhooks, nil
+}
+
+// EnqueueDeliveries stores new deliveries. Deliveries that are already queued
+// are skipped, so enqueueing the same event twice is harmless. Delivered
+// deliveries past their retention are removed on the way.
+func (dao *WebhookDAO) EnqueueDeliveries(ctx context.Context, deliveries ...models.WebhookDelivery) error {
+	const op = "storage.postgres.EnqueueDeliveries"
+
+	batch := &pgx.Batch{}
+	batch.Queue(
+		"DELETE FROM webhook_deliveries WHERE status = $1 AND created_at < $2",
+		models.DeliveryStatusDelivered, time.Now().UTC().Add(-deliveredRetention),
+	)
+
+	for _, delivery := range deliveries {
+		event, err := json.Marshal(delivery.Event)
+		if err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+
+		history, err := json.Marshal(delivery.History)
+		if err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+		if delivery.History == nil {
+			history = []byte("[]")
+		}
+
+		batch.Queue(
+			"INSERT INTO webhook_deliveries ("+deliveryColumns+") VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9) ON CONFLICT (id) DO NOTHING",
+			delivery.ID, delivery.WebhookID, event, delivery.Status, delivery.Attempts,
+			delivery.NextAttemptAt, delivery.LastError, history, delivery.CreatedAt,
+		)
+	}
+
+	if err := conn(ctx, dao.pool).SendBatch(ctx, batch).Close(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// ClaimDueDelivery picks a pending delivery whose next attempt is due and
+// postpones it by lease so that other workers skip it while it is in flight.
+// It returns storage.ErrDeliveryNotFound when nothing is due.
+func (dao *WebhookDAO) ClaimDueDelivery(ctx context.Context, now time.Time, lease time.Duration) (models.WebhookDelivery, error) {
+	const op = "storage.postgres.ClaimDueDelivery"
+
+	row := conn(ctx, dao.pool).QueryRow(ctx,
+		`UPDATE webhook_deliveries SET next_attempt_at = $3
+		WHERE id = (
+			SELECT id FROM webhook_deliveries
+			WHERE status = $1 AND next_attempt_at <= $2
+			ORDER BY next_attempt_at
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING `+deliveryColumns,
+		models.DeliveryStatusPending, now, now.Add(lease),
+	)
+
+	delivery, err := scanDelivery(row)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return models.WebhookDelivery{}, fmt.Errorf("%s: %w", op, storage.ErrDeliveryNotFound)
+		}
+
+		return models.WebhookDelivery{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return delivery, nil
+}
+
+// RecordAttempt stores the outcome of a delivery attempt together with the new
+// status and the time of the next attempt.
+func (dao *WebhookDAO) RecordAttempt(ctx context.Context, deliveryID string, attempt models.DeliveryAttempt, status string, nextAttemptAt time.Time) error {
+	const op = "storage.postgres.RecordAttempt"
+
+	history, err := json.Marshal([]models.DeliveryAttempt{attempt})
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	_, err = conn(ctx, dao.pool).Exec(ctx,
+		`UPDATE webhook_deliveries
+		SET status = $2, next_attempt_at = $3, last_error = $4, attempts = attempts + 1, history = history || $5::jsonb
+		WHERE id = $1`,
+		deliveryID, status, nextAttemptAt, attempt.Error, history,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (dao *WebhookDAO) DeadDeliveries(ctx context.Context, limit int64) ([]models.WebhookDelivery, error) {
+	const op = "storage.postgres.DeadDeliveries"
+
+	rows, err := conn(ctx, dao.pool).Query(ctx,
+		"SELECT "+deliveryColumns+" FROM webhook_deliveries WHERE status = $1 ORDER BY created_at DESC LIMIT $2",
+		models.DeliveryStatusDead, limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var deliveries []models.WebhookDelivery
+
+	for rows.Next() {
+		delivery, err := scanDelivery(rows)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		deliveries = append(deliveries, delivery)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return deliveries, nil
+}
+
+// ReplayDelivery moves a dead delivery back to the queue with a fresh attempt
+// budget. The attempt history is kept.
+func (dao *WebhookDAO) ReplayDelivery(ctx context.Context, deliveryID string) error {
+	const op = "storage.postgres.ReplayDelivery"
+
+	tag, err := conn(ctx, dao.pool).Exec(ctx,
+		"UPDATE webhook_deliveries SET status = $3, attempts = 0, next_attempt_at = $4 WHERE id = $1 AND status = $2",
+		deliveryID, models.DeliveryStatusDead, models.DeliveryStatusPending, time.Now().UTC(),
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrDeliveryNotFound)
+	}
+
+	return nil
+}
+
+// EraseUserDeliveries clears the personal data of the events of the user in
+// every delivery, whatever its status.
+func (dao *WebhookDAO) EraseUserDeliveries(ctx context.Context, userID int64) error {
+	const op = "storage.postgres.EraseUserDeliveries"
+
+	_, err := conn(ctx, dao.pool).Exec(ctx,
+		`UPDATE webhook_deliveries SET event = event - 'login' - 'telegram_login'
+		WHERE (event->>'user_id')::BIGINT = $1 AND event ?| ARRAY['login', 'telegram_login']`,
+		userID,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func scanWebhook(row pgx.Row) (models.Webhook, error) {
+	var webhook models.Webhook
+
+	err := row.Scan(&webhook.ID, &webhook.URL, &webhook.Secret, &webhook.EventTypes, &webhook.CreatedBy, &webhook.CreatedAt)
+	if err != nil {
+		return models.Webhook{}, err
+	}
+
+	webhook.CreatedAt = webhook.CreatedAt.UTC()
+
+	return webhook, nil
+}
+
+func scanDelivery(row pgx.Row) (models.WebhookDelivery, error) {
+	var (
+		delivery models.WebhookDelivery
+		event    []byte
+		history  []byte
+	)
+
+	err := row.Scan(
+		&delivery.ID, &delivery.WebhookID, &event, &delivery.Status, &delivery.Attempts,
+		&delivery.NextAttemptAt, &delivery.LastError, &history, &delivery.CreatedAt,
+	)
+	if err != nil {
+		return models.WebhookDelivery{}, err
+	}
+
+	if err := json.Unmarshal(event, &delivery.Event); err != nil {
+		return models.WebhookDelivery{}, err
+	}
+	if err := json.Unmarshal(history, &delivery.History); err != nil {
+		return models.WebhookDelivery{}, err
+	}
+
+	return delivery, nil
+}
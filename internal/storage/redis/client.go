@@ -0,0 +1,104 @@
+package redis
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/j0n1que/sso-service/internal/config"
+)
+
+// Connect opens a single-node, Sentinel or Cluster client as selected by cfg
+// and pings it so that an unreachable or misconfigured redis fails at startup.
+func Connect(ctx context.Context, cfg config.TokensStorageConfig) (redis.UniversalClient, error) {
+	const op = "storage.redis.Connect"
+
+	addrs := cfg.Addrs
+	if len(addrs) == 0 {
+		if cfg.Addr == "" {
+			return nil, fmt.Errorf("%s: no redis address configured", op)
+		}
+		addrs = []string{cfg.Addr}
+	}
+
+	opts := &redis.UniversalOptions{
+		Addrs:            addrs,
+		DB:               cfg.DB,
+		Username:         cfg.Username,
+		Password:         cfg.Password,
+		SentinelPassword: cfg.SentinelPassword,
+		MasterName:       cfg.MasterName,
+		MaxRetries:       cfg.MaxRetries,
+		DialTimeout:      cfg.DialTimeout,
+		ReadTimeout:      cfg.ReadTimeout,
+		WriteTimeout:     cfg.WriteTimeout,
+		PoolSize:         cfg.PoolSize,
+		MinIdleConns:     cfg.MinIdleConns,
+		PoolTimeout:      cfg.PoolTimeout,
+		IdleTimeout:      cfg.IdleTimeout,
+	}
+
+	if cfg.TLS.Enabled {
+		tlsConfig, err := clientTLS(cfg.TLS)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		opts.TLSConfig = tlsConfig
+	}
+
+	var client redis.UniversalClient
+	switch {
+	case cfg.Cluster && cfg.MasterName != "":
+		return nil, fmt.Errorf("%s: cluster and sentinel master name are mutually exclusive", op)
+	case cfg.Cluster:
+		if cfg.DB != 0 {
+			return nil, fmt.Errorf("%s: redis cluster supports only db 0", op)
+		}
+		// NewUniversalClient picks a cluster client only for several addresses
+		client = redis.NewClusterClient(opts.Cluster())
+	default:
+		client = redis.NewUniversalClient(opts)
+	}
+
+	if err := client.Ping(ctx).Err(); err != nil {
+		_ = client.Close()
+		return nil, fmt.Errorf("%s: failed to ping redis at %v: %w", op, addrs, err)
+	}
+
+	return client, nil
+}
+
+func clientTLS(cfg config.RedisTLSConfig) (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		ServerName:         cfg.ServerName,
+		InsecureSkipVerify: cfg.InsecureSkipVerify,
+	}
+
+	if cfg.CAFile != "" {
+		pem, err := os.ReadFile(cfg.CAFile)
+		if err != nil {
+			return nil, err
+		}
+
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, errors.New("no certificates found in redis CA file")
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if cfg.CertFile != "" || cfg.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(cfg.CertFile, cfg.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return tlsConfig, nil
+}
@@ -3,34 +3,61 @@ package redis
 import (
 	"context"
 	"fmt"
+	"sync/atomic"
 	"time"
 
 	"github.com/go-redis/redis/v8"
+	"github.com/j0n1que/sso-service/internal/lib/metrics"
+	"github.com/j0n1que/sso-service/internal/lib/tracing"
 	"github.com/j0n1que/sso-service/internal/storage"
 )
 
+// TokenStorage keeps user tokens under keyPrefix so that several services
+// can share one redis.
 type TokenStorage struct {
-	db *redis.Client
+	db        redis.UniversalClient
+	keyPrefix string
 }
 
-func New(addr, password string) *TokenStorage {
+func New(client redis.UniversalClient, keyPrefix string) *TokenStorage {
 	return &TokenStorage{
-		db: redis.NewClient(&redis.Options{
-			Addr:     addr,
-			Password: password,
-			DB:       0,
-		}),
+		db:        client,
+		keyPrefix: keyPrefix,
 	}
 }
 
+func (db *TokenStorage) key(userID int64) string {
+	return fmt.Sprintf("%suser:%d", db.keyPrefix, userID)
+}
+
 func (db *TokenStorage) Close() {
 	db.db.Close()
 }
 
-func (db *TokenStorage) JWT(ctx context.Context, userID int64) (string, error) {
+func (db *TokenStorage) Ping(ctx context.Context) (err error) {
+	const op = "storage.redis.Ping"
+
+	defer metrics.ObserveStorage("redis", op, time.Now(), &err)
+
+	ctx, span := tracing.Start(ctx, op)
+	defer tracing.End(span, &err)
+
+	if err := db.db.Ping(ctx).Err(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (db *TokenStorage) JWT(ctx context.Context, userID int64) (_ string, err error) {
 	const op = "storage.redis.JWT"
 
-	key := fmt.Sprintf("user:%d", userID)
+	defer metrics.ObserveStorage("redis", op, time.Now(), &err)
+
+	ctx, span := tracing.Start(ctx, op)
+	defer tracing.End(span, &err)
+
+	key := db.key(userID)
 
 	token, err := db.db.Get(ctx, key).Result()
 	if err != nil {
@@ -43,10 +70,39 @@ func (db *TokenStorage) JWT(ctx context.Context, userID int64) (string, error) {
 	return token, nil
 }
 
-func (db *TokenStorage) SaveJWT(ctx context.Context, token string, userID int64, ttl time.Duration) error {
+// JWTTTL returns the remaining lifetime of the user's token.
+func (db *TokenStorage) JWTTTL(ctx context.Context, userID int64) (_ time.Duration, err error) {
+	const op = "storage.redis.JWTTTL"
+
+	defer metrics.ObserveStorage("redis", op, time.Now(), &err)
+
+	ctx, span := tracing.Start(ctx, op)
+	defer tracing.End(span, &err)
+
+	key := db.key(userID)
+
+	ttl, err := db.db.TTL(ctx, key).Result()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	// redis reports -2 for a missing key and -1 for a key without expiration
+	if ttl == -2 {
+		return 0, fmt.Errorf("%s: %w", op, storage.ErrTokenNotFound)
+	}
+
+	return ttl, nil
+}
+
+func (db *TokenStorage) SaveJWT(ctx context.Context, token string, userID int64, ttl time.Duration) (err error) {
 	const op = "storage.redis.SaveJWT"
 
-	key := fmt.Sprintf("user:%d", userID)
+	defer metrics.ObserveStorage("redis", op, time.Now(), &err)
+
+	ctx, span := tracing.Start(ctx, op)
+	defer tracing.End(span, &err)
+
+	key := db.key(userID)
 
 	wasSet, err := db.db.SetNX(ctx, key, token, ttl).Result()
 	if err != nil {
@@ -60,15 +116,109 @@ func (db *TokenStorage) SaveJWT(ctx context.Context, token string, userID int64,
 	return nil
 }
 
-func (db *TokenStorage) DeleteJWT(ctx context.Context, userID int64) error {
+func (db *TokenStorage) DeleteJWT(ctx context.Context, userID int64) (err error) {
 	const op = "storage.redis.DeleteJWT"
 
-	key := fmt.Sprintf("user:%d", userID)
+	defer metrics.ObserveStorage("redis", op, time.Now(), &err)
 
-	err := db.db.Del(ctx, key).Err()
+	ctx, span := tracing.Start(ctx, op)
+	defer tracing.End(span, &err)
+
+	key := db.key(userID)
+
+	err = db.db.Del(ctx, key).Err()
 	if err != nil {
 		return fmt.Errorf("%s: %w", op, err)
 	}
 
 	return nil
 }
+
+// RevokeToken denies the token with the given ID for ttl, which should be the
+// remaining lifetime of the token.
+func (db *TokenStorage) RevokeToken(ctx context.Context, jti string, ttl time.Duration) (err error) {
+	const op = "storage.redis.RevokeToken"
+
+	defer metrics.ObserveStorage("redis", op, time.Now(), &err)
+
+	ctx, span := tracing.Start(ctx, op)
+	defer tracing.End(span, &err)
+
+	if err := db.db.Set(ctx, db.keyPrefix+"revoked:"+jti, 1, ttl).Err(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (db *TokenStorage) IsTokenRevoked(ctx context.Context, jti string) (_ bool, err error) {
+	const op = "storage.redis.IsTokenRevoked"
+
+	defer metrics.ObserveStorage("redis", op, time.Now(), &err)
+
+	ctx, span := tracing.Start(ctx, op)
+	defer tracing.End(span, &err)
+
+	n, err := db.db.Exists(ctx, db.keyPrefix+"revoked:"+jti).Result()
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return n > 0, nil
+}
+
+// CountSessions returns the number of users that have a stored token.
+func (db *TokenStorage) CountSessions(ctx context.Context) (_ int64, err error) {
+	const op = "storage.redis.CountSessions"
+
+	defer metrics.ObserveStorage("redis", op, time.Now(), &err)
+
+	ctx, span := tracing.Start(ctx, op)
+	defer tracing.End(span, &err)
+
+	pattern := db.keyPrefix + "user:*"
+
+	// a cluster client scans only one node, so every master is scanned
+	if cluster, ok := db.db.(*redis.ClusterClient); ok {
+		var count atomic.Int64
+
+		err := cluster.ForEachMaster(ctx, func(ctx context.Context, node *redis.Client) error {
+			n, err := countKeys(ctx, node, pattern)
+			count.Add(n)
+			return err
+		})
+		if err != nil {
+			return 0, fmt.Errorf("%s: %w", op, err)
+		}
+
+		return count.Load(), nil
+	}
+
+	count, err := countKeys(ctx, db.db, pattern)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return count, nil
+}
+
+func countKeys(ctx context.Context, db redis.Cmdable, pattern string) (int64, error) {
+	var (
+		cursor uint64
+		count  int64
+	)
+
+	for {
+		keys, next, err := db.Scan(ctx, cursor, pattern, 1000).Result()
+		if err != nil {
+			return 0, err
+		}
+
+		count += int64(len(keys))
+
+		cursor = next
+		if cursor == 0 {
+			return count, nil
+		}
+	}
+}
@@ -7,4 +7,33 @@ var (
 	ErrTokenExists   = errors.New("token for that user already exists")
 	ErrUserNotFound  = errors.New("user not found")
 	ErrTokenNotFound = errors.New("token for that user not found")
+	// ErrIDCollision means no free user ID was found. Unlike ErrUserExists it
+	// says nothing about the user being registered.
+	ErrIDCollision = errors.New("no free user id found")
+	// ErrConflict means the user was changed concurrently and the update was
+	// not applied.
+	ErrConflict = errors.New("user was modified concurrently")
+
+	ErrWebhookNotFound  = errors.New("webhook not found")
+	ErrDeliveryNotFound = errors.New("webhook delivery not found")
 )
+
+// IsExpected reports whether err is a storage outcome that is part of normal
+// operation rather than a failure.
+func IsExpected(err error) bool {
+	for _, expected := range []error{
+		ErrUserExists,
+		ErrTokenExists,
+		ErrUserNotFound,
+		ErrTokenNotFound,
+		ErrConflict,
+		ErrWebhookNotFound,
+		ErrDeliveryNotFound,
+	} {
+		if errors.Is(err, expected) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,51 @@
+package storage
+
+import (
+	"context"
+	"sync"
+)
+
+type commitHooksKey struct{}
+
+type commitHooks struct {
+	mu  sync.Mutex
+	fns []func()
+}
+
+// AfterCommit runs fn once the transaction of ctx commits, and never if it is
+// rolled back. Outside a transaction fn runs right away.
+func AfterCommit(ctx context.Context, fn func()) {
+	hooks, ok := ctx.Value(commitHooksKey{}).(*commitHooks)
+	if !ok {
+		fn()
+		return
+	}
+
+	hooks.mu.Lock()
+	defer hooks.mu.Unlock()
+
+	hooks.fns = append(hooks.fns, fn)
+}
+
+// WithCommitHooks returns a copy of ctx collecting the functions passed to
+// AfterCommit, and a function running them that transactors call once the
+// transaction has committed. A transaction nested in another one leaves the
+// hooks to the outer one.
+func WithCommitHooks(ctx context.Context) (context.Context, func()) {
+	if _, ok := ctx.Value(commitHooksKey{}).(*commitHooks); ok {
+		return ctx, func() {}
+	}
+
+	hooks := &commitHooks{}
+
+	return context.WithValue(ctx, commitHooksKey{}, hooks), func() {
+		hooks.mu.Lock()
+		fns := hooks.fns
+		hooks.fns = nil
+		hooks.mu.Unlock()
+
+		for _, fn := range fns {
+			fn()
+		}
+	}
+}